@@ -0,0 +1,172 @@
+package colprint
+
+import (
+	"bytes"
+	"io"
+)
+
+// defaultSampleRows is the number of rows a Stream measures when
+// Options.SampleRows is not set.
+const defaultSampleRows = 100
+
+// Fit sizes the columns from rows when the program was compiled with
+// Options.AutoWidth, then rebuilds the header and column writers. Without
+// AutoWidth it does nothing.
+//
+// Each column becomes as wide as its widest value, bounded by the field's
+// MinWidth and MaxWidth, but never narrower than its header. Call Fit
+// before writing; it must not run concurrently with WriteRow.
+func (p *Program[T]) Fit(rows []T) {
+	if !p.opts.AutoWidth {
+		return
+	}
+
+	// Measure every requested field, including any a narrow line dropped
+	cols := make([]compiledCol[T], len(p.fields))
+	for j, f := range p.fields {
+		cols[j] = makeWriter(f, false, &p.opts)
+	}
+
+	widths := make([]int, len(cols))
+	var tmp []byte
+	for i := range rows {
+		for j := range cols {
+			col := &cols[j]
+			tmp = col.value(tmp[:0], &rows[i])
+			if w := valueWidth(tmp, col.wrap); w > widths[j] {
+				widths[j] = w
+			}
+		}
+	}
+
+	for j := range p.fields {
+		p.fields[j].Width = fitWidth(p.fields[j], widths[j])
+	}
+	p.build()
+}
+
+// valueWidth returns the display width of a formatted value. Values of
+// wrapped columns are measured by their widest line.
+func valueWidth(val []byte, wrap bool) int {
+	if !wrap {
+		return textWidth(val)
+	}
+	widest := 0
+	for len(val) > 0 {
+		end := bytes.IndexByte(val, '\n')
+		if end < 0 {
+			end = len(val)
+		}
+		if w := textWidth(val[:end]); w > widest {
+			widest = w
+		}
+		val = val[min(end+1, len(val)):]
+	}
+	return widest
+}
+
+// fitWidth bounds a measured width by the field's limits and its header.
+func fitWidth[T any](f Field[T], w int) int {
+	if f.MinWidth > 0 && w < f.MinWidth {
+		w = f.MinWidth
+	}
+	if f.MaxWidth > 0 && w > f.MaxWidth {
+		w = f.MaxWidth
+	}
+	if hw := textWidth(f.Display); w < hw {
+		w = hw
+	}
+	return w
+}
+
+// Stream writes the rows of a Program to w, taking care of the header.
+//
+// In AutoWidth mode the first Options.SampleRows rows are held back. Once
+// the sample is full, or on Flush, the program is fitted to the sample,
+// the widths are locked, and the header, underline and held rows are
+// written. Later rows are written straight through with zero allocations.
+// Without AutoWidth the header is written before the first row.
+//
+// A Stream is not safe for concurrent use.
+type Stream[T any] struct {
+	prog   *Program[T]
+	w      io.Writer
+	size   int
+	sample []T
+	locked bool
+	tmp    []byte
+	line   []byte
+}
+
+// Stream returns a Stream that writes the program's output to w.
+func (p *Program[T]) Stream(w io.Writer) *Stream[T] {
+	size := 0
+	if p.opts.AutoWidth {
+		size = p.opts.SampleRows
+		if size <= 0 {
+			size = defaultSampleRows
+		}
+	}
+	return &Stream[T]{
+		prog: p,
+		w:    w,
+		size: size,
+		tmp:  make([]byte, 0, 64),
+		line: make([]byte, 0, 256),
+	}
+}
+
+// WriteRow writes v, or holds a copy of it while the sample is collected.
+func (s *Stream[T]) WriteRow(v *T) error {
+	if !s.locked {
+		if len(s.sample) < s.size {
+			s.sample = append(s.sample, *v)
+			if len(s.sample) < s.size {
+				return nil
+			}
+			return s.Flush()
+		}
+		if err := s.Flush(); err != nil {
+			return err
+		}
+	}
+	return s.prog.WriteRow(s.w, v, &s.tmp, &s.line)
+}
+
+// Flush locks the column widths if that has not happened yet, and writes
+// the header and any held rows. Call it after the last row; it is safe to
+// call more than once.
+func (s *Stream[T]) Flush() error {
+	if s.locked {
+		return nil
+	}
+	s.locked = true
+
+	p := s.prog
+	p.Fit(s.sample)
+
+	if err := p.WriteHeader(s.w, &s.line); err != nil {
+		return err
+	}
+	if !p.opts.NoUnderline {
+		if err := p.WriteUnderline(s.w, &s.line); err != nil {
+			return err
+		}
+	}
+	for i := range s.sample {
+		if err := p.WriteRow(s.w, &s.sample[i], &s.tmp, &s.line); err != nil {
+			return err
+		}
+	}
+	s.sample = nil
+	return nil
+}
+
+// Close flushes the stream and writes the program's footer, which closes
+// the array in FormatJSON. Call it once, after the last row.
+func (s *Stream[T]) Close() error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	return s.prog.WriteFooter(s.w, &s.line)
+}
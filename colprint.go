@@ -14,6 +14,7 @@
 //   - Single syscall per row with line buffering
 //   - Support for collections and default field sets
 //   - Custom formatters for complex types
+//   - Unicode-aware padding measured in terminal cells
 //   - Suitable for streaming and batch processing
 //
 // # Basic Usage
@@ -58,6 +59,34 @@
 //	// Use @collection syntax in specs
 //	prog, _ := colprint.Compile(reg, "@basic,custom_field")
 //
+// The Collection builder adds a description and can include other
+// collections; a collection without a default spec expands to its members:
+//
+//	reg.Collection("all_info", "Everything about a person").
+//	    Fields("@basic", "city", "country").
+//	    Register()
+//
+// @default expands to the registry default set with SetDefault, or to the
+// collection chosen with SetDefaults. Views defined with DefineView build
+// on each other, so "@wide" can mean "@long plus a few more":
+//
+//	reg.DefineView("long", "default", "ppid,stime")
+//
+// # Output Formats
+//
+// Besides aligned tables, a program can write RFC 4180 CSV or TSV, JSON
+// Lines, a JSON array, a Markdown table or an HTML table by setting
+// Options.Format. Delimited output quotes values as needed and never
+// truncates them; JSON output keys each row object by field name and keeps
+// numbers as numbers; Markdown and HTML escape their special characters:
+//
+//	prog, _ := colprint.CompileWithOptions(reg, "name,age", colprint.Options{
+//	    Format: colprint.FormatCSV,
+//	})
+//
+// FormatJSON and FormatHTML also need WriteFooter (or Stream.Close) after
+// the last row, to close the array or table.
+//
 // # Performance
 //
 // The library is designed for maximum performance:
@@ -69,14 +98,58 @@
 //
 // Expected performance: 1M+ rows/sec for typical workloads.
 //
-// # Current Limitations
+// # Automatic Widths
+//
+// With Options.AutoWidth, column widths come from the data. Batch callers
+// measure every row with Program.Fit; streaming callers write through a
+// Stream, which holds back the first Options.SampleRows rows, sizes the
+// columns from them and then writes everything else straight through:
+//
+//	prog, _ := colprint.CompileWithOptions(reg, "name,age", colprint.Options{
+//	    Separator: "  ",
+//	    AutoWidth: true,
+//	})
+//	prog.Fit(people)
+//
+// Widths stay within Field.MinWidth and Field.MaxWidth, and a column is
+// never narrower than its header.
+//
+// # Narrow Terminals
+//
+// Options.MaxLineWidth (or Options.FitTerminal) limits the total line
+// width. Columns marked Shrinkable are narrowed first; if that is not
+// enough, columns are dropped in order of increasing Priority. The dropped
+// columns are reported by Program.Dropped.
 //
-// This initial version supports left-alignment only. Future versions will
-// add right-alignment support for numeric fields.
+// # Alignment
+//
+// Each field is left-aligned unless configured otherwise with
+// FieldBuilder.Align. Values can also be right-aligned, centered, or lined
+// up on the decimal point, and headers follow the alignment of their column:
+//
+//	reg.Field("cpu", "CPU%", "CPU usage").
+//	    Width(6).
+//	    Align(colprint.AlignDecimal).
+//	    Float(1, func(p *Proc) float64 { return p.CPU }).
+//	    Register()
+//
+// Alignment can be overridden per column in a spec with a prefix on the
+// width: "<" left, ">" right, "^" center and "=" decimal point, as in
+// "name:>20" or "cpu:=".
+//
+// # Truncation
+//
+// Values wider than their column are cut at the column edge by default.
+// FieldBuilder.Truncate selects a visible alternative: a trailing, leading
+// or middle ellipsis, letting the value overflow into the next column, or
+// filling the column with '#' as spreadsheets do for numbers. In a spec the
+// strategy is given as an extra modifier, as in "path:30:lead" or
+// "id:12:middle" (see Compile for the keywords).
 package colprint
 
 import (
 	"io"
+	"time"
 )
 
 // Kind represents the data type of a field.
@@ -91,6 +164,65 @@ const (
 	KindFloat
 	// KindCustom indicates a custom formatter function.
 	KindCustom
+	// KindTime indicates a time.Time field.
+	KindTime
+	// KindDuration indicates a time.Duration field.
+	KindDuration
+	// KindInt64 indicates an int64 field.
+	KindInt64
+	// KindUint64 indicates a uint64 field.
+	KindUint64
+	// KindSize indicates a byte count, shown with units such as "1.5Gi".
+	KindSize
+)
+
+// isNumeric reports whether fields of kind k hold numbers.
+func isNumeric(k Kind) bool {
+	switch k {
+	case KindInt, KindInt64, KindUint64, KindFloat, KindDuration, KindSize:
+		return true
+	}
+	return false
+}
+
+// Align controls how a value is positioned within its column.
+type Align int
+
+const (
+	// AlignLeft pads values on the right. This is the default.
+	AlignLeft Align = iota
+	// AlignRight pads values on the left.
+	AlignRight
+	// AlignCenter splits the padding evenly, with any odd space on the right.
+	AlignCenter
+	// AlignDecimal lines values up on the decimal point.
+	//
+	// The column reserves room for Precision fraction digits right of the
+	// point; values without a '.' are aligned as integers. Headers of
+	// decimal-aligned columns are right-aligned.
+	AlignDecimal
+)
+
+// Truncate controls what happens to a value wider than its column.
+type Truncate int
+
+const (
+	// TruncateCut cuts the value at the column edge. This is the default.
+	TruncateCut Truncate = iota
+	// TruncateEllipsis keeps the start of the value and ends it with "…".
+	TruncateEllipsis
+	// TruncateLeading keeps the end of the value and starts it with "…".
+	// Useful for paths, where the last elements matter most.
+	TruncateLeading
+	// TruncateMiddle keeps both ends of the value with "…" between them.
+	// Useful for identifiers and hashes.
+	TruncateMiddle
+	// TruncateOverflow writes the value in full, spilling into the next
+	// column and pushing the rest of the row to the right.
+	TruncateOverflow
+	// TruncateHash fills the column with '#' characters, the way
+	// spreadsheets flag numbers that do not fit.
+	TruncateHash
 )
 
 // Field describes how to extract and format a field from type T.
@@ -107,31 +239,147 @@ type Field[T any] struct {
 	// Description provides help text for this field
 	Description string
 
-	// Width is the column width in characters
+	// Aliases are other names the field can be selected by
+	Aliases []string
+
+	// Deprecated hides the field from help, @all and patterns, and makes
+	// Compile warn when it is used. If Replacement names another field,
+	// that field is used instead.
+	Deprecated  bool
+	Replacement string
+
+	// Width is the column width in terminal cells
 	Width int
 
+	// MinWidth and MaxWidth bound the width chosen by automatic sizing
+	// (0 means unbounded). An explicit width in a spec sets both.
+	MinWidth int
+	MaxWidth int
+
+	// Priority decides which columns survive a narrow line: the lowest
+	// priority column is dropped first (default 0)
+	Priority int
+
+	// Shrinkable lets the column be narrowed, down to MinWidth or its
+	// header width, to fit a narrow line
+	Shrinkable bool
+
 	// Kind indicates the data type (String, Int, Float, Custom)
 	Kind Kind
 
-	// Precision specifies decimal places for Float fields
+	// Precision specifies decimal places for Float fields, and significant
+	// digits for Size fields and SI-scaled Floats. For other kinds it is
+	// the number of fraction digits reserved by AlignDecimal.
 	Precision int
 
+	// Align positions the value within the column (default AlignLeft)
+	Align Align
+
+	// Truncate selects how values wider than Width are shortened
+	// (default TruncateCut)
+	Truncate Truncate
+
+	// Wrap word-wraps values wider than Width onto continuation lines
+	// instead of truncating them
+	Wrap bool
+
+	// Format is a format hint, such as "%e" for a Float or "%Y-%m-%d" for
+	// a custom formatter that understands it (see GetCustomFormat). Integer
+	// fields take a radix such as "%#x", Size fields a style such as
+	// "iec", Time fields a layout and Duration fields a DurationStyle name.
+	Format string
+
+	// Unit is appended to the values of Float fields scaled with SI
+	// prefixes (see FieldBuilder.Scaled), as in "Hz" or " ops/s"
+	Unit string
+
+	// Location is the time zone Time fields are shown in; nil shows each
+	// value in its own
+	Location *time.Location
+
+	// Number groups the digits and sets the separators of Int and Float
+	// fields; nil uses Options.Number
+	Number *NumberFormat
+
 	// Value extractors - only one should be set based on Kind
 	GetString func(*T) string
 	GetInt    func(*T) int
 	GetFloat  func(*T) float64
 	GetCustom func(dst []byte, v *T) []byte
 
-	// Future: Align field will be added here for Phase 2
-	// Align Align  // Left or Right alignment
+	GetTime     func(*T) time.Time
+	GetDuration func(*T) time.Duration
+	GetInt64    func(*T) int64
+	GetUint64   func(*T) uint64
+
+	// GetCustomFormat is a Custom formatter that also receives Format.
+	// When set, it is used instead of GetCustom.
+	GetCustomFormat func(dst []byte, v *T, format string) []byte
+
+	// RawJSON marks a Custom field whose output is already valid JSON, to
+	// be embedded as-is in JSON output instead of as a string
+	RawJSON bool
+}
+
+// Format selects the output format of a Program.
+type Format int
+
+const (
+	// FormatTable writes aligned, fixed-width columns. This is the default.
+	FormatTable Format = iota
+	// FormatCSV writes RFC 4180 comma-separated values.
+	FormatCSV
+	// FormatTSV writes tab-separated values, quoted like FormatCSV.
+	FormatTSV
+	// FormatJSONLines writes one JSON object per row, keyed by field name.
+	FormatJSONLines
+	// FormatJSON writes a JSON array of objects, keyed by field name.
+	// WriteHeader opens the array and WriteFooter closes it.
+	FormatJSON
+	// FormatMarkdown writes a GitHub-flavored Markdown table. WriteHeader
+	// writes the header and the delimiter row; NoPadding makes it compact.
+	FormatMarkdown
+	// FormatHTML writes an HTML table, one <tr> per row. WriteHeader opens
+	// the table and writes the <thead>; WriteFooter closes it.
+	FormatHTML
+)
+
+// CSVOptions configures FormatCSV and FormatTSV output.
+//
+// Values are written in full: Width, alignment, truncation and wrapping
+// do not apply. A value is quoted when it contains the delimiter, the
+// quote character, a line break, or starts with a space; quote characters
+// inside it are doubled.
+type CSVOptions struct {
+	// Delimiter separates values (default: ',' for CSV, '\t' for TSV)
+	Delimiter rune
+
+	// Quote encloses values that need quoting (default: '"')
+	Quote rune
+
+	// LineTerminator ends each row (default: "\r\n", as RFC 4180 requires)
+	LineTerminator string
+
+	// BOM writes a UTF-8 byte order mark before the header row, which
+	// helps spreadsheet applications detect the encoding
+	BOM bool
+
+	// HeaderNames uses field names instead of Display text in the header
+	HeaderNames bool
 }
 
 // Options configures program compilation.
 type Options struct {
+	// Format selects the output format (default: FormatTable)
+	Format Format
+
+	// CSV configures FormatCSV and FormatTSV output
+	CSV CSVOptions
+
 	// Separator is inserted between columns (default: "  ")
 	Separator string
 
-	// NoPadding disables all column padding (useful for CSV)
+	// NoPadding disables all column padding and alignment (useful for CSV)
 	NoPadding bool
 
 	// PadLastColumn pads the last column to its width (default: false)
@@ -143,13 +391,50 @@ type Options struct {
 
 	// NoUnderline skips underline generation
 	NoUnderline bool
+
+	// AutoWidth sizes columns from the data instead of Field.Width.
+	// Call Program.Fit with all rows (batch), or write through a Stream,
+	// which measures the first SampleRows rows and then locks the widths.
+	AutoWidth bool
+
+	// SampleRows is the number of rows a Stream measures before locking
+	// column widths in AutoWidth mode (default: 100)
+	SampleRows int
+
+	// MaxLineWidth is the widest a line may be, in cells (0 means no
+	// limit). Shrinkable columns are narrowed and low-priority columns
+	// dropped until the line fits; see Program.Dropped.
+	MaxLineWidth int
+
+	// FitTerminal uses the width of the terminal on stdout (or $COLUMNS)
+	// as MaxLineWidth when MaxLineWidth is not set. Nothing is limited
+	// when no terminal width is known.
+	FitTerminal bool
+
+	// Now returns the time that Time fields with LayoutRelative are
+	// measured from (default: time.Now)
+	Now func() time.Time
+
+	// Number groups the digits and sets the separators of Int and Float
+	// fields that have no NumberFormat of their own (default: none)
+	Number NumberFormat
+
+	// NonFinite sets how Float fields write NaN and infinities
+	// (default: "NaN", "+Inf" and "-Inf")
+	NonFinite NonFinite
 }
 
 // compiledCol is an optimized, type-specialized column writer.
 type compiledCol[T any] struct {
 	width int
-	// Future: align field will be added here
+	align Align
+	cell  cell
+	wrap  bool
+	// write appends the padded cell; value appends the raw, unpadded value;
+	// json appends the value encoded as JSON
 	write func(line *[]byte, v *T, tmp *[]byte)
+	value func(dst []byte, v *T) []byte
+	json  func(dst []byte, v *T) []byte
 }
 
 // Program is a compiled, optimized formatting plan for type T.
@@ -157,27 +442,71 @@ type compiledCol[T any] struct {
 // Programs are created by Compile() and can be reused for formatting
 // millions of rows with zero allocations.
 type Program[T any] struct {
+	fields    []Field[T] // as requested by the spec
+	shown     []Field[T] // after fitting the line width
+	spec      string     // canonical form of the spec
+	warnings  []SpecWarning
+	layouts   []Layout // inline definitions in the spec
+	dropped   []string
+	opts      Options
+	csv       CSVOptions // resolved from opts for FormatCSV and FormatTSV
+	maxWidth  int
+	head      []byte // everything WriteHeader writes
+	foot      []byte // everything WriteFooter writes
 	header    []byte
 	underline []byte
 	separator []byte
+	newline   []byte // row terminator
+	jsonKeys  [][]byte
+	mdCells   []cell
+	htmlCells [][]byte // opening <td> tag of each column
+	started   bool     // FormatJSON: a row was written since WriteHeader
 	columns   []compiledCol[T]
+	wrapCols  int // number of columns with Wrap set
 }
 
 // WriteHeader writes the column headers to w.
 //
 // The line buffer is used for temporary storage and reused across calls.
 // It should have adequate capacity (typically 256 bytes).
+//
+// For FormatJSON it opens the array. Nothing is written for FormatJSONLines
+// or when Options.NoHeader is set, apart from a CSV byte order mark.
 func (p *Program[T]) WriteHeader(w io.Writer, line *[]byte) error {
-	*line = append((*line)[:0], p.header...)
-	*line = append(*line, '\n')
+	p.started = false
+	if len(p.head) == 0 {
+		return nil
+	}
+	*line = append((*line)[:0], p.head...)
+	_, err := w.Write(*line)
+	return err
+}
+
+// WriteFooter writes whatever must follow the last row.
+//
+// FormatJSON closes its array and FormatHTML its table; for other formats
+// nothing is written. Calling it anyway keeps callers format-agnostic.
+func (p *Program[T]) WriteFooter(w io.Writer, line *[]byte) error {
+	if len(p.foot) == 0 {
+		return nil
+	}
+	*line = (*line)[:0]
+	if p.opts.Format == FormatJSON && p.started {
+		*line = append(*line, '\n')
+	}
+	*line = append(*line, p.foot...)
 	_, err := w.Write(*line)
 	return err
 }
 
 // WriteUnderline writes the header underline to w.
 //
-// The underline uses dashes under text and spaces elsewhere.
+// The underline uses dashes under text and spaces elsewhere. Formats
+// other than FormatTable have no underline, and nothing is written.
 func (p *Program[T]) WriteUnderline(w io.Writer, line *[]byte) error {
+	if p.opts.Format != FormatTable {
+		return nil
+	}
 	*line = append((*line)[:0], p.underline...)
 	*line = append(*line, '\n')
 	_, err := w.Write(*line)
@@ -186,25 +515,96 @@ func (p *Program[T]) WriteUnderline(w io.Writer, line *[]byte) error {
 
 // WriteRow formats and writes a single row to w.
 //
+// In FormatJSON the program tracks whether a row has been written since
+// WriteHeader, so a Program in that format must not be shared between
+// concurrent writers.
+//
 // This is the hot path - designed for zero allocations and maximum speed.
 // Buffers tmp and line are reused across calls and should have adequate
 // capacity (typically 64 and 256 bytes respectively).
 //
 // The tmp buffer is used for formatting individual values. The line buffer
-// accumulates the complete row before writing.
+// accumulates the complete row before writing. When the program has
+// wrapped columns, one row may span several lines; they are still written
+// with a single call to w.
 func (p *Program[T]) WriteRow(w io.Writer, v *T, tmp, line *[]byte) error {
 	*line = (*line)[:0]
-	for i := range p.columns {
-		if i > 0 {
-			*line = append(*line, p.separator...)
+	if p.opts.Format == FormatJSON {
+		// Array elements are separated, not terminated; the newline after
+		// the last one comes from WriteFooter.
+		if p.started {
+			*line = append(*line, ",\n"...)
 		}
-		p.columns[i].write(line, v, tmp)
+		p.started = true
 	}
-	*line = append(*line, '\n')
+	p.appendRow(v, tmp, line)
+	*line = append(*line, p.newline...)
 	_, err := w.Write(*line)
 	return err
 }
 
+// Column describes a column of a compiled program.
+type Column struct {
+	Name    string
+	Display string
+	Width   int
+	Kind    Kind
+	Align   Align
+}
+
+// Columns describes the columns the program writes, in order. Widths are
+// the ones in effect, after Fit and after fitting Options.MaxLineWidth;
+// dropped columns are not included.
+func (p *Program[T]) Columns() []Column {
+	cols := make([]Column, len(p.shown))
+	for i, f := range p.shown {
+		cols[i] = Column{
+			Name:    f.Name,
+			Display: f.Display,
+			Width:   f.Width,
+			Kind:    f.Kind,
+			Align:   f.Align,
+		}
+	}
+	return cols
+}
+
+// Spec returns the canonical form of the spec the program was compiled
+// from: every field by name, with views, collections, patterns and
+// removals expanded, and with modifiers for exactly the settings that
+// differ from the registry. Compiling it against the same registry selects
+// the same columns, which makes it suitable for saving a user's layout.
+//
+// Widths found by Fit are not included.
+func (p *Program[T]) Spec() string {
+	return p.spec
+}
+
+// Warnings returns what Compile found worth changing in the spec, such as
+// deprecated fields and the replacements used for them.
+func (p *Program[T]) Warnings() []SpecWarning {
+	warnings := make([]SpecWarning, len(p.warnings))
+	copy(warnings, p.warnings)
+	return warnings
+}
+
+// Layouts returns the collections defined inline in the spec, as in
+// "@mine=pid,cmd,rss:12", each with its canonical spec. Save them with
+// WriteLayouts to offer them again on the next run.
+func (p *Program[T]) Layouts() []Layout {
+	layouts := make([]Layout, len(p.layouts))
+	copy(layouts, p.layouts)
+	return layouts
+}
+
+// Dropped returns the names of the columns left out to fit
+// Options.MaxLineWidth, in the order they were dropped.
+func (p *Program[T]) Dropped() []string {
+	names := make([]string, len(p.dropped))
+	copy(names, p.dropped)
+	return names
+}
+
 // HeaderString returns the header as a string.
 func (p *Program[T]) HeaderString() string {
 	return string(p.header)
@@ -216,11 +616,35 @@ func (p *Program[T]) HeaderString() string {
 // Prefer WriteRow for high-volume output.
 func (p *Program[T]) FormatRow(v *T, tmp, line *[]byte) string {
 	*line = (*line)[:0]
+	p.appendRow(v, tmp, line)
+	return string(*line)
+}
+
+// appendRow appends the formatted row to line, without a newline.
+func (p *Program[T]) appendRow(v *T, tmp, line *[]byte) {
+	*tmp = (*tmp)[:0]
+	switch p.opts.Format {
+	case FormatCSV, FormatTSV:
+		p.appendDelimitedRow(v, tmp, line)
+		return
+	case FormatJSONLines, FormatJSON:
+		p.appendJSONRow(v, tmp, line)
+		return
+	case FormatMarkdown:
+		p.appendMarkdownRow(v, tmp, line)
+		return
+	case FormatHTML:
+		p.appendHTMLRow(v, tmp, line)
+		return
+	}
+	if p.wrapCols > 0 {
+		p.appendWrappedRow(v, tmp, line)
+		return
+	}
 	for i := range p.columns {
 		if i > 0 {
 			*line = append(*line, p.separator...)
 		}
 		p.columns[i].write(line, v, tmp)
 	}
-	return string(*line)
 }
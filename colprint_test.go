@@ -2,8 +2,14 @@ package colprint
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 type testPerson struct {
@@ -171,204 +177,2868 @@ func TestCompileDefault(t *testing.T) {
 	}
 }
 
+func TestDefaultResolution(t *testing.T) {
+	newReg := func() *Registry[testPerson] {
+		reg := NewRegistry[testPerson]()
+		reg.Field("name", "Name", "Test").String((*testPerson).GetName).Register()
+		reg.Field("age", "Age", "Test").Int((*testPerson).GetAge).Register()
+		reg.Field("temp", "Temp", "Test").Float(1, (*testPerson).GetTemp).Register()
+		reg.DefineCollection("basic", "name,age", "name", "age")
+		reg.DefineCollection("health", "temp", "temp")
+		return reg
+	}
+
+	// Two collections with defaults and nothing chosen: ambiguous
+	reg := newReg()
+	for i := 0; i < 10; i++ {
+		_, err := Compile(reg, "@default")
+		if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+			t.Fatalf("expected ambiguity error, got %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		setup    func(reg *Registry[testPerson])
+		expected string
+	}{
+		{"SetDefaults", func(reg *Registry[testPerson]) { reg.SetDefaults("health", "temp,name") }, "Temp  Name"},
+		{"SetDefault", func(reg *Registry[testPerson]) {
+			reg.SetDefault("age")
+			reg.SetDefaults("health", "temp")
+		}, "Age"},
+		{"collection named default", func(reg *Registry[testPerson]) {
+			reg.DefineCollection("default", "name", "name")
+		}, "Name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := newReg()
+			tt.setup(reg)
+			prog, err := CompileWithOptions(reg, "@default", Options{Separator: "  ", NoPadding: true})
+			if err != nil {
+				t.Fatalf("compile failed: %v", err)
+			}
+			if got := prog.HeaderString(); got != tt.expected {
+				t.Errorf("expected header %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	// Help marks the collection @default expands to
+	reg = newReg()
+	reg.SetDefaults("health", "temp")
+	var buf bytes.Buffer
+	reg.PrintHelp(&buf, "")
+	if !strings.Contains(buf.String(), "@health           Default: temp  (@default)") {
+		t.Errorf("expected active default in help, got:\n%s", buf.String())
+	}
+}
+
+func TestViews(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+	reg.Field("name", "Name", "Test").String((*testPerson).GetName).Register()
+	reg.Field("age", "Age", "Test").Int((*testPerson).GetAge).Register()
+	reg.Field("temp", "Temp", "Test").Float(1, (*testPerson).GetTemp).Register()
+
+	reg.SetDefault("name")
+	reg.DefineView("long", "default", "age")
+	reg.DefineView("wide", "long", "temp")
+	reg.DefineView("loop", "loop", "")
+
+	tests := []struct {
+		spec     string
+		expected string
+	}{
+		{"@default", "Name"},
+		{"@long", "Name Age"},
+		{"@wide", "Name Age Temp"},
+		{"temp,@long", "Temp Name Age"},
+	}
+
+	for _, tt := range tests {
+		prog, err := CompileWithOptions(reg, tt.spec, Options{Separator: " ", NoPadding: true})
+		if err != nil {
+			t.Fatalf("compile %q failed: %v", tt.spec, err)
+		}
+		if got := prog.HeaderString(); got != tt.expected {
+			t.Errorf("%q: expected header %q, got %q", tt.spec, tt.expected, got)
+		}
+	}
+
+	if _, err := Compile(reg, "@loop"); err == nil {
+		t.Error("expected error for a view that refers to itself")
+	}
+
+	var buf bytes.Buffer
+	reg.PrintHelp(&buf, "")
+	if !strings.Contains(buf.String(), "@long             @default,age") {
+		t.Errorf("expected views in help, got:\n%s", buf.String())
+	}
+}
+
+func TestSpecSetOperations(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+	reg.Field("name", "Name", "Test").String((*testPerson).GetName).Register()
+	reg.Field("age", "Age", "Test").Int((*testPerson).GetAge).Register()
+
+	io := NewRegistryWithName[testPerson]("IO")
+	io.Field("io_read", "Read", "Test").Int((*testPerson).GetAge).Register()
+	io.Field("io_write", "Write", "Test").Int((*testPerson).GetAge).Register()
+	io.Field("temp", "Temp", "Test").Float(1, (*testPerson).GetTemp).Register()
+	reg.AddRegistry(io)
+
+	reg.DefineCollection("io", "io_*", "io_read", "io_write")
+	reg.SetDefault("name,age,temp")
+
+	tests := []struct {
+		spec     string
+		expected string
+	}{
+		{"@all", "Name Age Read Write Temp"},
+		{"@default,-age", "Name Temp"},
+		{"@all,-@io", "Name Age Temp"},
+		{"IO_*", "Read Write"},
+		{"@all,-io_*,-name", "Age Temp"},
+		{"?ame,a*", "Name Age"},
+		{"age,@default", "Age Name Temp"},
+		{"name,name,age,name", "Name Age"},
+		{"name,-name,age,name", "Age Name"},
+	}
+
+	for _, tt := range tests {
+		prog, err := CompileWithOptions(reg, tt.spec, Options{Separator: " ", NoPadding: true})
+		if err != nil {
+			t.Fatalf("compile %q failed: %v", tt.spec, err)
+		}
+		if got := prog.HeaderString(); got != tt.expected {
+			t.Errorf("%q: expected header %q, got %q", tt.spec, tt.expected, got)
+		}
+	}
+
+	// The first occurrence keeps its overrides
+	prog, _ := Compile(reg, "age:3,@default")
+	if prog.columns[0].width != 3 {
+		t.Errorf("expected first occurrence width 3, got %d", prog.columns[0].width)
+	}
+
+	for _, spec := range []string{"zz_*", "-", "name,-age:5", "io_[", "-name"} {
+		if _, err := Compile(reg, spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestParseFieldSpecModifiers(t *testing.T) {
+	tests := []struct {
+		tok      string
+		expected colSpec
+	}{
+		{"cpu:8.1", colSpec{name: "cpu", width: 8, hasWidth: true, prec: 1, hasPrec: true}},
+		{"name:>20", colSpec{name: "name", width: 20, hasWidth: true, align: AlignRight, hasAlign: true}},
+		{"cpu:=.3", colSpec{name: "cpu", prec: 3, hasPrec: true, align: AlignDecimal, hasAlign: true}},
+		{"rss=Memory", colSpec{name: "rss", display: "Memory", hasDisplay: true}},
+		{"rss = Resident Set :12:ELLIPSIS", colSpec{name: "rss", display: "Resident Set", hasDisplay: true,
+			width: 12, hasWidth: true, trunc: TruncateEllipsis, hasTrunc: true}},
+		{"start:%Y-%m-%d", colSpec{name: "start", format: "%Y-%m-%d", hasFormat: true}},
+		{"start:10:%H:%M:%S", colSpec{name: "start", width: 10, hasWidth: true, format: "%H:%M:%S", hasFormat: true}},
+		{"desc:wrap:40", colSpec{name: "desc", width: 40, hasWidth: true, wrap: true}},
+	}
+
+	for _, tt := range tests {
+		cs, err := parseFieldSpec(tt.tok)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.tok, err)
+			continue
+		}
+		if cs != tt.expected {
+			t.Errorf("%q: expected %+v, got %+v", tt.tok, tt.expected, cs)
+		}
+	}
+
+	errors := map[string]string{
+		"cpu:":        "empty modifier",
+		"cpu:8.x":     "invalid precision",
+		"cpu:>x":      "invalid width",
+		"cpu:bold":    "unknown modifier",
+		"rss=:8":      "empty header",
+		"=Memory":     "missing field name",
+		"start:8:%":   "empty format",
+		"cpu:8.-1":    "invalid precision",
+		"cpu:12abc":   "invalid width",
+		"cpu:8:wrap:": "empty modifier",
+	}
+	for tok, want := range errors {
+		_, err := parseFieldSpec(tok)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error containing %q, got %v", tok, want, err)
+		}
+	}
+}
+
+func TestSpecModifiers(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("temp", "Temp", "Test").
+		Float(2, (*testPerson).GetTemp).
+		Register()
+
+	reg.Field("stamp", "Stamp", "Test").
+		CustomFormat(func(dst []byte, p *testPerson, format string) []byte {
+			dst = append(dst, format...)
+			return append(dst, p.Name...)
+		}).
+		Format("#").
+		Register()
+
+	prog, err := CompileWithOptions(reg, "name=Who:>5,temp:8.1,stamp:%x:", Options{Separator: "|", PadLastColumn: true})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	if got, want := prog.HeaderString(), "  Who|Temp    |Stamp     "; got != want {
+		t.Errorf("expected header %q, got %q", want, got)
+	}
+
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+	person := testPerson{Name: "Al", Temp: 36.66}
+	if got, want := prog.FormatRow(&person, &tmp, &line), "   Al|36.7    |%x:Al     "; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	prog, _ = CompileWithOptions(reg, "temp:%e,stamp", Options{Separator: "|", NoPadding: true})
+	if got, want := prog.FormatRow(&person, &tmp, &line), "3.67e+01|#Al"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	for _, spec := range []string{"name:%s", "temp:%q", "name:8.2"} {
+		if _, err := Compile(reg, spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	spec := " pid, -@io ,rss=Mem: >12 :%x:y,io_*"
+	ast, err := ParseSpec(spec)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	if len(ast.Items) != 4 {
+		t.Fatalf("expected 4 items, got %d", len(ast.Items))
+	}
+
+	pid, io, rss, glob := ast.Items[0], ast.Items[1], ast.Items[2], ast.Items[3]
+	if pid.Kind != ItemField || pid.Name != "pid" || pid.Pos != 1 || pid.End != 4 {
+		t.Errorf("unexpected pid item: %+v", pid)
+	}
+	if io.Kind != ItemRef || !io.Remove || io.Name != "io" || io.Pos != 6 || io.NamePos != 8 {
+		t.Errorf("unexpected io item: %+v", io)
+	}
+	if rss.Name != "rss" || rss.Header != "Mem" || len(rss.Modifiers) != 2 {
+		t.Fatalf("unexpected rss item: %+v", rss)
+	}
+	if m := rss.Modifiers[0]; m.Text != ">12" || spec[m.Pos:m.Pos+len(m.Text)] != m.Text {
+		t.Errorf("unexpected modifier: %+v", m)
+	}
+	if m := rss.Modifiers[1]; m.Text != "%x:y" || spec[m.Pos:m.Pos+len(m.Text)] != m.Text {
+		t.Errorf("unexpected format modifier: %+v", m)
+	}
+	if glob.Kind != ItemPattern || glob.Name != "io_*" {
+		t.Errorf("unexpected pattern item: %+v", glob)
+	}
+
+	if got, want := ast.String(), "pid,-@io,rss=Mem:>12:%x:y,io_*"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	for _, bad := range []string{"-", "@", "@io:5", "-pid:5", "pid:"} {
+		if _, err := ParseSpec(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestProgramIntrospection(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+	reg.Field("name", "Name", "Test").Width(8).String((*testPerson).GetName).Register()
+	reg.Field("age", "Age", "Test").Width(3).Align(AlignRight).Int((*testPerson).GetAge).Register()
+	reg.Field("temp", "Temp", "Test").Width(6).Float(2, (*testPerson).GetTemp).Register()
+	reg.DefineCollection("basic", "name,age", "name", "age")
+
+	prog, err := Compile(reg, "@basic, -age, temp=Deg:^8.1:%e, age:ellipsis")
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	want := "name,temp=Deg:^8.1:%e,age:ellipsis"
+	if got := prog.Spec(); got != want {
+		t.Errorf("expected spec %q, got %q", want, got)
+	}
+
+	// The canonical spec compiles to the same columns
+	again, err := Compile(reg, prog.Spec())
+	if err != nil {
+		t.Fatalf("recompile failed: %v", err)
+	}
+	if again.Spec() != want || again.HeaderString() != prog.HeaderString() {
+		t.Errorf("round trip changed the program: %q, %q", again.Spec(), again.HeaderString())
+	}
+
+	cols := prog.Columns()
+	expected := []Column{
+		{Name: "name", Display: "Name", Width: 8, Kind: KindString, Align: AlignLeft},
+		{Name: "temp", Display: "Deg", Width: 8, Kind: KindFloat, Align: AlignCenter},
+		{Name: "age", Display: "Age", Width: 3, Kind: KindInt, Align: AlignRight},
+	}
+	if len(cols) != len(expected) {
+		t.Fatalf("expected %d columns, got %d", len(expected), len(cols))
+	}
+	for i := range expected {
+		if cols[i] != expected[i] {
+			t.Errorf("column %d: expected %+v, got %+v", i, expected[i], cols[i])
+		}
+	}
+}
+
+func TestSpecErrors(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+	reg.Field("name", "Name", "Test").String((*testPerson).GetName).Register()
+
+	sub := NewRegistryWithName[testPerson]("More")
+	sub.Field("temp", "Temp", "Test").Float(1, (*testPerson).GetTemp).Register()
+	reg.AddRegistry(sub)
+	reg.DefineCollection("basic", "name", "name")
+
+	spec := "nmae, tmep:8, name:warp, @basci, name:5.x"
+	_, err := Compile(reg, spec)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	var errs SpecErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected SpecErrors, got %T: %v", err, err)
+	}
+
+	expected := []struct {
+		pos         int
+		token       string
+		suggestions []string
+	}{
+		{0, "nmae", []string{"name"}},
+		{6, "tmep", []string{"temp"}},
+		{19, "warp", []string{"wrap"}},
+		{25, "@basci", []string{"@basic"}},
+		{40, "x", nil},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %d: %v", len(expected), len(errs), err)
+	}
+	for i, want := range expected {
+		e := errs[i]
+		if e.Pos != want.pos || e.Token != want.token || e.Spec != spec {
+			t.Errorf("error %d: expected %q at %d, got %q at %d", i, want.token, want.pos, e.Token, e.Pos)
+		}
+		if fmt.Sprint(e.Suggestions) != fmt.Sprint(want.suggestions) {
+			t.Errorf("error %d: expected suggestions %v, got %v", i, want.suggestions, e.Suggestions)
+		}
+	}
+
+	var first *SpecError
+	if !errors.As(err, &first) || first != errs[0] {
+		t.Fatal("expected errors.As to find the first *SpecError")
+	}
+	if got, want := first.Error(), `unknown field "nmae" at offset 0; did you mean "name"?`; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got, want := errs[1].Caret(), spec+"\n      ^~~~"; got != want {
+		t.Errorf("expected caret:\n%s\ngot:\n%s", want, got)
+	}
+
+	// Modifiers the field rejects are reported at the modifier
+	for spec, want := range map[string]string{
+		"temp, name:<0":         "temp, name:<0\n           ^~",
+		"name:<20:utc":          "name:<20:utc\n         ^~~",
+		"temp:8.1:de, name:3.1": "temp:8.1:de, name:3.1\n                  ^~~",
+	} {
+		_, err := Compile(reg, spec)
+		if !errors.As(err, &first) {
+			t.Errorf("%s: expected a *SpecError, got %v", spec, err)
+		} else if got := first.Caret(); got != want {
+			t.Errorf("%s: expected caret:\n%s\ngot:\n%s", spec, want, got)
+		}
+	}
+
+	// An error inside a view is reported at the reference, wrapping the
+	// error found in the view's own spec
+	reg.DefineView("bad", "", "name,tmpe")
+	_, err = Compile(reg, "temp,@bad")
+	if !errors.As(err, &first) {
+		t.Fatalf("expected a *SpecError, got %v", err)
+	}
+	if first.Pos != 5 || first.Token != "@bad" || fmt.Sprint(first.Suggestions) != "[temp]" {
+		t.Errorf("expected @bad at 5 suggesting temp, got %q at %d suggesting %v", first.Token, first.Pos, first.Suggestions)
+	}
+	if got, want := err.Error(), `expanding @bad: unknown field "tmpe" at offset 5; did you mean "temp"?`; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	var inner SpecErrors
+	if !errors.As(first.Err, &inner) || inner[0].Spec != "name,tmpe" || inner[0].Pos != 5 {
+		t.Errorf("expected the view's own error to be wrapped, got %#v", first.Err)
+	}
+	if err := reg.Validate(); err == nil || !strings.Contains(err.Error(), `@bad: unknown field "tmpe" at offset 5`) ||
+		strings.Contains(err.Error(), "expanding @bad") {
+		t.Errorf("expected the view error reported once, got %v", err)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"name", "name", 0},
+		{"nmae", "name", 1},
+		{"nam", "name", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestAliasesAndDeprecation(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Full name").
+		Alias("fullname", "nm").
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("age", "Age", "Age in years").
+		Int((*testPerson).GetAge).
+		Register()
+
+	reg.Field("years", "Years", "Old age column").
+		Deprecated("age").
+		Register()
+
+	reg.Field("temp", "Temp", "Temperature").
+		Deprecated("").
+		Float(1, (*testPerson).GetTemp).
+		Register()
+
+	prog, err := CompileWithOptions(reg, "FullName,years:>5,temp", Options{Separator: " ", NoPadding: true})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	if got, want := prog.HeaderString(), "Name Age Temp"; got != want {
+		t.Errorf("expected header %q, got %q", want, got)
+	}
+	if got, want := prog.Spec(), "name,age:>5,temp"; got != want {
+		t.Errorf("expected spec %q, got %q", want, got)
+	}
+
+	warnings := prog.Warnings()
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	if w := warnings[0]; w.Token != "years" || w.Pos != 9 || w.Replacement != "age" {
+		t.Errorf("unexpected warning: %+v", w)
+	}
+	if w := warnings[1]; w.Token != "temp" || w.Replacement != "" || !strings.Contains(w.Msg, "deprecated") {
+		t.Errorf("unexpected warning: %+v", w)
+	}
+
+	// Deprecated fields are left out of @all and help
+	prog, _ = CompileWithOptions(reg, "@all", Options{Separator: " ", NoPadding: true})
+	if got, want := prog.HeaderString(), "Name Age"; got != want {
+		t.Errorf("expected header %q, got %q", want, got)
+	}
+
+	var buf bytes.Buffer
+	reg.PrintHelp(&buf, "")
+	help := buf.String()
+	if !strings.Contains(help, "Full name (alias: fullname, nm)") || strings.Contains(help, "Old age column") {
+		t.Errorf("unexpected help:\n%s", help)
+	}
+
+	buf.Reset()
+	reg.PrintHelpWithOptions(&buf, HelpOptions{ShowDeprecated: true})
+	if !strings.Contains(buf.String(), "Old age column (deprecated, use age)") {
+		t.Errorf("expected deprecated field in help:\n%s", buf.String())
+	}
+}
+
+type testPair struct {
+	ID   int
+	A, B testPerson
+}
+
+func TestNamespacedLookup(t *testing.T) {
+	people := NewRegistry[testPerson]()
+	people.Field("name", "Name", "Full name").
+		String((*testPerson).GetName).
+		Register()
+	people.Field("age", "Age", "Age in years").
+		Int((*testPerson).GetAge).
+		Register()
+
+	first := NewRegistryWithName[testPair]("First Person")
+	InheritFieldsFrom(first, people, func(p *testPair) *testPerson { return &p.A }, WithNamespace("a"))
+	first.Field("temp", "Temp", "Temperature").
+		Float(1, func(p *testPair) float64 { return p.A.Temp }).
+		Register()
+
+	second := NewRegistryWithName[testPair]("b")
+	InheritFieldsFrom(second, people, func(p *testPair) *testPerson { return &p.B })
+
+	reg := NewRegistry[testPair]()
+	reg.Field("age", "ID", "Pair ID").
+		Int(func(p *testPair) int { return p.ID }).
+		Register()
+	reg.AddRegistry(first)
+	reg.AddRegistry(second)
+
+	opts := Options{Format: FormatCSV}
+	prog, err := CompileWithOptions(reg, "A.Name,b.name,age,b.age,temp", opts)
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	if got, want := prog.Spec(), "a.name,b.name,age,b.age,temp"; got != want {
+		t.Errorf("expected spec %q, got %q", want, got)
+	}
+
+	var tmp, line []byte
+	var buf bytes.Buffer
+	pair := testPair{ID: 7, A: testPerson{Name: "Alice", Age: 30, Temp: 36.6}, B: testPerson{Name: "Bob", Age: 25}}
+	prog.WriteRow(&buf, &pair, &tmp, &line)
+	if got, want := buf.String(), "Alice,Bob,7,25,36.6\r\n"; got != want {
+		t.Errorf("expected row %q, got %q", want, got)
+	}
+
+	// The canonical spec compiles back to the same columns
+	again, err := CompileWithOptions(reg, prog.Spec(), opts)
+	if err != nil || again.Spec() != prog.Spec() {
+		t.Errorf("canonical spec did not round-trip: %v", err)
+	}
+
+	// Shared names are qualified in @all and patterns
+	prog, _ = CompileWithOptions(reg, "@all", opts)
+	if got, want := prog.Spec(), "age,a.name,a.age,temp,b.name,b.age"; got != want {
+		t.Errorf("expected @all spec %q, got %q", want, got)
+	}
+	prog, _ = CompileWithOptions(reg, "a.*", opts)
+	if got, want := prog.Spec(), "a.name,a.age,temp"; got != want {
+		t.Errorf("expected pattern spec %q, got %q", want, got)
+	}
+
+	// Unqualified names found in several sub-registries are ambiguous
+	_, err = Compile(reg, "age,name")
+	var serr *SpecError
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected a SpecError, got %v", err)
+	}
+	if serr.Pos != 4 || !strings.Contains(serr.Msg, "ambiguous") {
+		t.Errorf("unexpected error: %v", serr)
+	}
+	if got, want := strings.Join(serr.Suggestions, " "), "a.name b.name"; got != want {
+		t.Errorf("expected suggestions %q, got %q", want, got)
+	}
+}
+
+func TestCollections(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+	reg.Field("name", "Name", "Full name").
+		String((*testPerson).GetName).
+		Register()
+	reg.Field("age", "Age", "Age in years").
+		Int((*testPerson).GetAge).
+		Register()
+	reg.Field("temp", "Temp", "Temperature").
+		Float(1, (*testPerson).GetTemp).
+		Register()
+
+	reg.DefineCollection("basic", "name", "name", "age")
+	reg.Collection("health", "Health data").
+		Fields("temp", "age").
+		Register()
+	reg.Collection("everything", "All of it").
+		Fields("@basic", "@health").
+		Register()
+
+	opts := Options{Separator: " ", NoPadding: true}
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"@basic", "Name"},
+		{"@health", "Temp Age"},
+		{"@everything", "Name Age Temp"},
+	}
+	for _, tt := range tests {
+		prog, err := CompileWithOptions(reg, tt.spec, opts)
+		if err != nil {
+			t.Errorf("%s: compile failed: %v", tt.spec, err)
+			continue
+		}
+		if got := prog.HeaderString(); got != tt.want {
+			t.Errorf("%s: expected header %q, got %q", tt.spec, tt.want, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	reg.PrintHelp(&buf, "everything")
+	if help := buf.String(); !strings.Contains(help, "Temperature") || !strings.Contains(help, "Full name") {
+		t.Errorf("expected all members in help:\n%s", help)
+	}
+	buf.Reset()
+	reg.PrintHelp(&buf, "")
+	if help := buf.String(); !strings.Contains(help, "Health data") || !strings.Contains(help, "Fields: @basic,@health") ||
+		!strings.Contains(help, "Default: name") {
+		t.Errorf("expected collection descriptions in help:\n%s", help)
+	}
+
+	// Missing members and cycles are reported
+	reg.Collection("broken", "").Fields("name", "nope").Register()
+	reg.Collection("loop", "").Fields("@loop2").Register()
+	reg.Collection("loop2", "").Fields("age", "@loop").Register()
+	reg.Collection("dangling", "").Fields("name", "@nope").Register()
+	for spec, want := range map[string]string{
+		"@broken":   `collection @broken lists unknown field "nope"`,
+		"@loop":     "@loop refers to itself: @loop -> @loop2 -> @loop",
+		"@loop2":    "@loop2 refers to itself: @loop2 -> @loop -> @loop2",
+		"@dangling": `@dangling lists unknown collection "@nope"`,
+	} {
+		_, err := Compile(reg, spec)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected error containing %q, got %v", spec, want, err)
+		} else if msg := err.Error(); strings.Contains(msg, "expanding @") || strings.Count(msg, "refers to itself") > 1 {
+			t.Errorf("%s: expected the error once, got %v", spec, err)
+		}
+	}
+}
+
+func TestInheritCollections(t *testing.T) {
+	people := NewRegistry[testPerson]()
+	people.Field("name", "Name", "Full name").
+		String((*testPerson).GetName).
+		Register()
+	people.Field("age", "Age", "Age in years").
+		Int((*testPerson).GetAge).
+		Register()
+	people.Collection("basic", "The basics").
+		Fields("name", "age").
+		Default("name").
+		Register()
+
+	// Straight into the registry
+	flat := NewRegistry[testPair]()
+	InheritFieldsFrom(flat, people, func(p *testPair) *testPerson { return &p.A }, WithCollections())
+	prog, err := CompileWithOptions(flat, "@basic", Options{Format: FormatCSV})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	if got, want := prog.Spec(), "name"; got != want {
+		t.Errorf("expected spec %q, got %q", want, got)
+	}
+
+	// Through sub-registries sharing field names
+	reg := NewRegistry[testPair]()
+	for _, ns := range []string{"a", "b"} {
+		sub := NewRegistry[testPair]()
+		mapper := func(p *testPair) *testPerson { return &p.A }
+		if ns == "b" {
+			mapper = func(p *testPair) *testPerson { return &p.B }
+		}
+		InheritFieldsFrom(sub, people, mapper, WithNamespace(ns), WithCollections())
+		reg.AddRegistry(sub)
+	}
+	prog, err = CompileWithOptions(reg, "@b.basic,@a.basic,b.age", Options{Format: FormatCSV})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	if got, want := prog.Spec(), "b.name,a.name,b.age"; got != want {
+		t.Errorf("expected spec %q, got %q", want, got)
+	}
+}
+
+func TestRegistryValidate(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+	reg.Field("name", "Name", "Full name").
+		Alias("age").
+		String((*testPerson).GetName).
+		Register()
+	reg.Field("age", "Age", "Age in years").
+		Int((*testPerson).GetAge).
+		Register()
+	if err := reg.Validate(); err == nil || !strings.Contains(err.Error(), `alias "age" is already used by field "age"`) {
+		t.Errorf("expected alias clash, got %v", err)
+	}
+	if f, _ := reg.get("AGE"); f.Name != "age" {
+		t.Errorf("expected field names to win over aliases, got %q", f.Name)
+	}
+
+	reg = NewRegistry[testPerson]()
+	reg.Field("name", "Name", "Full name").
+		String((*testPerson).GetName).
+		Register()
+	reg.Field("name", "Name", "Again").
+		String((*testPerson).GetName).
+		Register()
+	reg.Field("age", "Age", "No getter").
+		Width(0).
+		Register()
+	reg.Field("a,b", "AB", "Bad name").
+		Float(1, (*testPerson).GetTemp).
+		Format("%x").
+		Register()
+	reg.DefineCollection("basic", "name,nope", "name", "missing")
+	reg.Collection("loop", "").Fields("@loop").Register()
+
+	if got := reg.ListFields(false); len(got) != 3 {
+		t.Errorf("expected the duplicate to be listed once, got %v", got)
+	}
+	if f, _ := reg.get("name"); f.Description != "Again" {
+		t.Errorf("expected the later definition to win, got %q", f.Description)
+	}
+
+	err := reg.Validate()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	want := []string{
+		`field "name": registered more than once`,
+		`field "age": width 0 must be positive`,
+		`field "age": no kind; use String, Int, Float, Time, Duration or Custom`,
+		`field "a,b": name cannot be used in a spec`,
+		`field "a,b": format "%x" is not valid for this field`,
+		`@basic: unknown field "missing"`,
+		`@basic: default spec "name,nope": unknown field "nope"`,
+		`@loop: @loop refers to itself: @loop -> @loop`,
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("expected %q in %v", w, err)
+		}
+	}
+	if len(verrs) != len(want) {
+		t.Errorf("expected %d errors, got %d: %v", len(want), len(verrs), err)
+	}
+
+	// Sub-registries are validated too, and named in the errors
+	sub := NewRegistryWithName[testPerson]("Extra")
+	sub.Field("temp", "Temp", "Temperature").Register()
+	root := NewRegistry[testPerson]()
+	root.AddRegistry(sub)
+	if err := root.Validate(); err == nil || !strings.HasPrefix(err.Error(), `Extra: field "temp"`) {
+		t.Errorf("expected sub-registry error, got %v", err)
+	}
+
+	valid := NewRegistry[testPerson]()
+	valid.Field("name", "Name", "Full name").
+		String((*testPerson).GetName).
+		Register()
+	valid.DefineCollection("basic", "name", "name")
+	valid.SetDefault("@basic")
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected a valid registry, got %v", err)
+	}
+}
+
+func TestMustRegister(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+	reg.Field("name", "Name", "Full name").
+		String((*testPerson).GetName).
+		MustRegister()
+
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(fmt.Sprint(r), "registered more than once") {
+			t.Errorf("expected a panic for the duplicate, got %v", r)
+		}
+	}()
+	reg.Field("name", "Name", "Again").
+		String((*testPerson).GetName).
+		MustRegister()
+}
+
+func TestOverrideInheritedField(t *testing.T) {
+	people := NewRegistry[testPerson]()
+	people.Field("name", "Name", "Full name").
+		Alias("n").
+		String((*testPerson).GetName).
+		Register()
+	people.Field("age", "Age", "Age in years").
+		Int((*testPerson).GetAge).
+		Register()
+
+	reg := NewRegistry[testPair]()
+	InheritFieldsFrom(reg, people, func(p *testPair) *testPerson { return &p.A })
+	reg.Field("name", "Who", "Name in capitals").
+		Width(6).
+		String(func(p *testPair) string { return strings.ToUpper(p.A.Name) }).
+		Register()
+
+	if got := reg.ListFields(false); fmt.Sprint(got) != "[name age]" {
+		t.Errorf("expected the override in the inherited place, got %v", got)
+	}
+	if _, ok := reg.get("n"); ok {
+		t.Error("expected the replaced field's alias to be dropped")
+	}
+
+	prog, err := CompileWithOptions(reg, "name,age", Options{Format: FormatCSV, NoHeader: true})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	var tmp, line []byte
+	pair := testPair{A: testPerson{Name: "Ada", Age: 36}}
+	if got, want := prog.FormatRow(&pair, &tmp, &line), "ADA,36"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if err := reg.Validate(); err != nil {
+		t.Errorf("expected the override to pass Validate, got %v", err)
+	}
+	reg.Field("age", "Age", "Age again").
+		Int(func(p *testPair) int { return p.A.Age }).
+		MustRegister()
+
+	// A second override is a mistake again
+	reg.Field("name", "Name", "Third").
+		String(func(p *testPair) string { return p.A.Name }).
+		Register()
+	if err := reg.Validate(); err == nil || !strings.Contains(err.Error(), `field "name": registered more than once`) {
+		t.Errorf("expected Validate to report the second override, got %v", err)
+	}
+}
+
+func TestInlineDefinitions(t *testing.T) {
+	ast, err := ParseSpec("@mine = name, age:>5 ; temp,@mine")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(ast.Items) != 3 || ast.Items[0].Kind != ItemDefine || len(ast.Items[0].Items) != 2 {
+		t.Fatalf("unexpected items: %+v", ast.Items)
+	}
+	if got, want := ast.String(), "@mine=name,age:>5;temp,@mine"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	reg := NewRegistry[testPerson]()
+	reg.Field("name", "Name", "Full name").
+		String((*testPerson).GetName).
+		Register()
+	reg.Field("age", "Age", "Age in years").
+		Int((*testPerson).GetAge).
+		Register()
+	reg.Field("temp", "Temp", "Temperature").
+		Float(1, (*testPerson).GetTemp).
+		Register()
+
+	prog, err := CompileWithOptions(reg, "@mine=name,age:>5;temp,-@mine", Options{Separator: " ", NoPadding: true})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	if got, want := prog.HeaderString(), "Temp"; got != want {
+		t.Errorf("expected header %q, got %q", want, got)
+	}
+	layouts := prog.Layouts()
+	if len(layouts) != 1 || layouts[0] != (Layout{Name: "mine", Spec: "name,age:>5"}) {
+		t.Errorf("unexpected layouts: %+v", layouts)
+	}
+
+	for spec, want := range map[string]string{
+		"@a=name,@b=age;":  "definitions cannot be nested",
+		"@a=;name":         `definition of "@a" lists no fields`,
+		"@a=name;@a=age":   `"@a" is defined more than once`,
+		"@a:5=name":        `invalid collection name "a:5"`,
+		"@a=name,nmae;@a":  `unknown field "nmae"`,
+		"@mine=name;@mien": `did you mean "@mine"`,
+	} {
+		_, err := Compile(reg, spec)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected error containing %q, got %v", spec, want, err)
+		}
+	}
+}
+
+func TestLayouts(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+	reg.Field("name", "Name", "Full name").
+		String((*testPerson).GetName).
+		Register()
+	reg.Field("age", "Age", "Age in years").
+		Int((*testPerson).GetAge).
+		Register()
+
+	layouts := []Layout{
+		{Name: "mine", Spec: "name:12,age"},
+		{Name: "more", Spec: "@mine,age=Years"},
+	}
+	for _, format := range []LayoutFormat{LayoutText, LayoutJSON} {
+		var buf bytes.Buffer
+		if err := WriteLayouts(&buf, layouts, format); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		got, err := ReadLayouts(&buf, format)
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(layouts) {
+			t.Errorf("format %d: expected %v, got %v", format, layouts, got)
+		}
+	}
+
+	text := "# saved columns\n\n@mine = name:12,age\nbad=nmae\n@more=@mine,age=Years\n@x,y=name\n"
+	err := reg.LoadLayouts(strings.NewReader(text), LayoutText)
+	if err == nil || !strings.Contains(err.Error(), `layout "bad": unknown field "nmae"`) ||
+		!strings.Contains(err.Error(), `layout "x,y": invalid name`) {
+		t.Errorf("expected errors for the bad layouts, got %v", err)
+	}
+
+	prog, err := CompileWithOptions(reg, "@more", Options{Separator: " ", NoPadding: true})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	if got, want := prog.Spec(), "name:12,age"; got != want {
+		t.Errorf("expected spec %q, got %q", want, got)
+	}
+
+	if _, err := ReadLayouts(strings.NewReader("no equals sign"), LayoutText); err == nil {
+		t.Error("expected an error for a malformed line")
+	}
+
+	// Definitions inside a layout or view are local to each expansion
+	if err := reg.AddLayouts(Layout{Name: "lay", Spec: "@z=name,age;name"}); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	reg.DefineView("view", "", "@w=age;@w")
+	for _, spec := range []string{"@lay,@lay", "@view,@view", "@z=age;@lay,@z", "@w=name;@view,@w"} {
+		prog, err := Compile(reg, spec)
+		if err != nil {
+			t.Errorf("%q: compile failed: %v", spec, err)
+			continue
+		}
+		if len(prog.Layouts()) > 1 {
+			t.Errorf("%q: expected only the spec's own definitions, got %v", spec, prog.Layouts())
+		}
+	}
+	if _, err := Compile(reg, "@lay,@z"); err == nil {
+		t.Error("expected a layout's definition not to leak into the spec")
+	}
+}
+
+type testEvent struct {
+	Start time.Time
+	Took  time.Duration
+}
+
+func TestTimeFields(t *testing.T) {
+	reg := NewRegistry[testEvent]()
+	reg.Field("start", "Start", "Start time").
+		Width(19).
+		Time("", func(e *testEvent) time.Time { return e.Start }).
+		Register()
+	reg.Field("clock", "Clock", "Start time in UTC").
+		Time(time.Kitchen, func(e *testEvent) time.Time { return e.Start }).
+		In(time.UTC).
+		Register()
+
+	zone := time.FixedZone("EST", -5*3600)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	opts := Options{Format: FormatTSV, Now: func() time.Time { return now }}
+
+	tests := []struct {
+		spec  string
+		start time.Time
+		want  string
+	}{
+		{"start", time.Date(2024, 3, 1, 9, 5, 7, 0, zone), "2024-03-01 09:05:07"},
+		{"start:utc", time.Date(2024, 3, 1, 9, 5, 7, 0, zone), "2024-03-01 14:05:07"},
+		{"start:%date", time.Date(2024, 3, 1, 9, 5, 7, 0, zone), "2024-03-01"},
+		{"start:%Y-%m-%d", time.Date(2024, 3, 1, 9, 5, 7, 0, zone), "2024-03-01"},
+		{"start:%Y", time.Date(2024, 3, 1, 9, 5, 7, 0, zone), "2024"},
+		{"start:%H:%M:%S %p %a %b %d%% %z %Z", time.Date(2024, 3, 1, 9, 5, 7, 0, zone), "09:05:07 AM Fri Mar 01% -0500 EST"},
+		{"start:%Z07:00", time.Date(2024, 3, 1, 9, 5, 7, 0, zone), "-05:00"},
+		{"start:%Jan 2 15:04", time.Date(2024, 3, 1, 9, 5, 7, 0, zone), "Mar 1 09:05"},
+		{"start:%Monday", time.Date(2024, 3, 1, 9, 5, 7, 0, zone), "Friday"},
+		{"start:utc:%15:04", time.Date(2024, 3, 1, 9, 5, 7, 0, zone), "14:05"},
+		{"clock", time.Date(2024, 3, 1, 9, 5, 7, 0, zone), "2:05PM"},
+		{"start:%unix", time.Unix(1700000000, 0), "1700000000"},
+		{"start:%relative", now.Add(-3 * time.Minute), "3m ago"},
+		{"start:%relative", now.Add(-50 * time.Hour), "2d ago"},
+		{"start:%relative", now.Add(2*time.Hour + time.Minute), "in 2h"},
+		{"start:%relative", now.Add(-400 * time.Millisecond), "now"},
+		{"start:%relative", time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC), "in 292y"},
+		{"start", time.Time{}, ""},
+	}
+	for _, tt := range tests {
+		prog, err := CompileWithOptions(reg, tt.spec, opts)
+		if err != nil {
+			t.Errorf("%s: compile failed: %v", tt.spec, err)
+			continue
+		}
+		var tmp, line []byte
+		var buf bytes.Buffer
+		prog.WriteRow(&buf, &testEvent{Start: tt.start}, &tmp, &line)
+		if got := strings.TrimSuffix(buf.String(), "\r\n"); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.spec, tt.want, got)
+		}
+	}
+
+	prog, _ := CompileWithOptions(reg, "start:utc", Options{Format: FormatJSONLines})
+	if got, want := prog.Spec(), "start:utc"; got != want {
+		t.Errorf("expected spec %q, got %q", want, got)
+	}
+	var tmp, line []byte
+	var buf bytes.Buffer
+	prog.WriteRow(&buf, &testEvent{Start: time.Date(2024, 3, 1, 9, 5, 7, 500, zone)}, &tmp, &line)
+	if got, want := buf.String(), `{"start":"2024-03-01T14:05:07.0000005Z"}`+"\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	if _, err := Compile(reg, "start,nope:utc"); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+
+	// Unknown strftime verbs would be printed literally by time.Format
+	for _, spec := range []string{"start:%Y-%j", "start:%q"} {
+		if _, err := Compile(reg, spec); err == nil || !strings.Contains(err.Error(), "%date") {
+			t.Errorf("%q: expected an error naming the layouts, got %v", spec, err)
+		}
+	}
+}
+
+func TestDurationFields(t *testing.T) {
+	tests := []struct {
+		d     time.Duration
+		style DurationStyle
+		prec  int
+		want  string
+	}{
+		{3723 * time.Second, DurationCompact, -1, "1h02m"},
+		{185 * time.Second, DurationCompact, -1, "3m05s"},
+		{45 * time.Second, DurationCompact, -1, "45s"},
+		{850 * time.Millisecond, DurationCompact, -1, "850ms"},
+		{120 * time.Microsecond, DurationCompact, -1, "120µs"},
+		{15, DurationCompact, -1, "15ns"},
+		{52 * time.Hour, DurationCompact, -1, "2d04h"},
+		{-90 * time.Second, DurationCompact, -1, "-1m30s"},
+		{62500 * time.Millisecond, DurationSeconds, -1, "62.5s"},
+		{62500 * time.Millisecond, DurationSeconds, 2, "62.50s"},
+		{3723 * time.Second, DurationClock, -1, "01:02:03"},
+		{26*time.Hour + 1500*time.Millisecond, DurationClock, 1, "26:00:01.5"},
+		{math.MinInt64, DurationCompact, -1, "-106751d23h"},
+		{math.MinInt64, DurationClock, 3, "-2562047:47:16.854"},
+		{math.MaxInt64, DurationCompact, -1, "106751d23h"},
+	}
+	for _, tt := range tests {
+		if got := string(appendDuration(nil, tt.d, tt.style, tt.prec)); got != tt.want {
+			t.Errorf("%v as %v: expected %q, got %q", tt.d, tt.style, tt.want, got)
+		}
+	}
+
+	reg := NewRegistry[testEvent]()
+	reg.Field("took", "Took", "Time taken").
+		Width(8).
+		Align(AlignRight).
+		Duration(DurationCompact, func(e *testEvent) time.Duration { return e.Took }).
+		Register()
+
+	ev := testEvent{Took: 3723500 * time.Millisecond}
+	for spec, want := range map[string]string{
+		"took":             "   1h02m",
+		"took:%clock":      "01:02:03",
+		"took:%seconds":    " 3723.5s",
+		"took:11.2:%clock": "01:02:03.50",
+	} {
+		prog, err := CompileWithOptions(reg, spec, Options{PadLastColumn: true})
+		if err != nil {
+			t.Errorf("%s: compile failed: %v", spec, err)
+			continue
+		}
+		var tmp, line []byte
+		if got := prog.FormatRow(&ev, &tmp, &line); got != want {
+			t.Errorf("%s: expected %q, got %q", spec, want, got)
+		}
+	}
+
+	if _, err := Compile(reg, "took:%hours"); err == nil || !strings.Contains(err.Error(), "%compact") {
+		t.Errorf("expected an error for an unknown style, got %v", err)
+	}
+	if _, err := Compile(reg, "took:utc"); err == nil {
+		t.Error("expected an error for a time zone on a duration")
+	}
+}
+
+func TestIntFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		n      int64
+		want   string
+	}{
+		{"", 1234, "1234"},
+		{"%d", -1234, "-1234"},
+		{"%05d", 42, "00042"},
+		{"%x", 255, "ff"},
+		{"%X", 255, "FF"},
+		{"%#x", 255, "0xff"},
+		{"%#08X", 0xbeef, "0X0000BEEF"},
+		{"%o", 0755, "755"},
+		{"%#o", 0755, "0755"},
+		{"%04o", 0644, "0644"},
+		{"%b", 5, "101"},
+		{"%#08b", 5, "0b00000101"},
+		{"%x", -255, "-ff"},
+		{"%d", math.MinInt64, "-9223372036854775808"},
+	}
+	for _, tt := range tests {
+		f, ok := parseIntFormat(tt.format)
+		if !ok {
+			t.Errorf("%q: not accepted", tt.format)
+			continue
+		}
+		if got := string(f.appendInt(nil, tt.n)); got != tt.want {
+			t.Errorf("%q of %d: expected %q, got %q", tt.format, tt.n, tt.want, got)
+		}
+	}
+
+	for _, bad := range []string{"x", "%", "%q", "%#d", "%0x", "%-8x", "%08", "%0999x"} {
+		if _, ok := parseIntFormat(bad); ok {
+			t.Errorf("%q: expected it to be rejected", bad)
+		}
+	}
+}
+
+type testFile struct {
+	Inode uint64
+	Size  int64
+	Mode  int
+}
+
+func TestInt64AndUint64(t *testing.T) {
+	reg := NewRegistry[testFile]()
+	reg.Field("inode", "Inode", "Inode number").
+		Width(20).
+		Uint64(func(f *testFile) uint64 { return f.Inode }).
+		Register()
+	reg.Field("size", "Size", "Size in bytes").
+		Width(20).
+		Int64(func(f *testFile) int64 { return f.Size }).
+		Register()
+	reg.Field("mode", "Mode", "Permissions").
+		Width(5).
+		Int(func(f *testFile) int { return f.Mode }).
+		Format("%#o").
+		Register()
+
+	file := testFile{Inode: math.MaxUint64, Size: -1 << 40, Mode: 0755}
+	opts := Options{Format: FormatCSV}
+
+	prog, err := CompileWithOptions(reg, "inode,size,mode", opts)
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	var tmp, line []byte
+	if got, want := prog.FormatRow(&file, &tmp, &line), "18446744073709551615,-1099511627776,0755"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	prog, err = CompileWithOptions(reg, "inode:%#x,mode:%d,size:%X", opts)
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	if got, want := prog.FormatRow(&file, &tmp, &line), "0xffffffffffffffff,493,-10000000000"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got, want := prog.Spec(), "inode:%#x,mode:%d,size:%X"; got != want {
+		t.Errorf("expected spec %q, got %q", want, got)
+	}
+
+	// JSON numbers stay decimal
+	prog, _ = CompileWithOptions(reg, "inode:%#x,size,mode", Options{Format: FormatJSONLines})
+	var buf bytes.Buffer
+	prog.WriteRow(&buf, &file, &tmp, &line)
+	if got, want := buf.String(), `{"inode":18446744073709551615,"size":-1099511627776,"mode":493}`+"\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	if _, err := Compile(reg, "mode:%e"); err == nil || !strings.Contains(err.Error(), "%#08x") {
+		t.Errorf("expected an error for a float format, got %v", err)
+	}
+}
+
+func TestSizeFormats(t *testing.T) {
+	const (
+		ki = 1024
+		mi = 1024 * ki
+		gi = 1024 * mi
+	)
+	tests := []struct {
+		format string
+		n      uint64
+		digits int
+		want   string
+	}{
+		{"", 3 * gi / 2, 3, "1.5Gi"},
+		{"iec", 512, 3, "512B"},
+		{"binary", 3 * gi / 2, 3, "1.5G"},
+		{"binary", 512, 3, "512"},
+		{"si", 1500000000, 3, "1.5G"},
+		{"si", 1500, 3, "1.5k"},
+		{"si-long", 1500000000, 3, "1.5 GB"},
+		{"iec-long", 3 * gi / 2, 3, "1.5 GiB"},
+		{"iec-long", 1, 3, "1 B"},
+		{"binary@M", 3 * gi / 2, 3, "1536M"},
+		{"iec@Mi", 3 * gi / 2, 3, "1536Mi"},
+		{"si@MB", 1234567, 3, "1.23M"},
+		{"iec@B", 3 * gi / 2, 3, "1610612736B"},
+		{"iec", 1234 * mi, 3, "1.21Gi"},
+		{"iec", 1234 * mi, 2, "1.2Gi"},
+		{"iec", 1234 * mi, 5, "1.2051Gi"},
+		{"iec", 1023*ki + 1000, 3, "1Mi"},
+		{"iec", 123*mi + 400*ki, 3, "123Mi"},
+		{"si", math.MaxUint64, 3, "18.4E"},
+	}
+	for _, tt := range tests {
+		f, ok := parseSizeFormat(tt.format)
+		if !ok {
+			t.Errorf("%q: not accepted", tt.format)
+			continue
+		}
+		if got := string(f.appendSize(nil, tt.n, tt.digits)); got != tt.want {
+			t.Errorf("%q of %d: expected %q, got %q", tt.format, tt.n, tt.want, got)
+		}
+	}
+
+	for _, bad := range []string{"%decimal", "iec@X", "iec@MM", "iec@", "@"} {
+		if _, ok := parseSizeFormat(bad); ok {
+			t.Errorf("%q: expected it to be rejected", bad)
+		}
+	}
+}
+
+func TestSIScaling(t *testing.T) {
+	tests := []struct {
+		v      float64
+		digits int
+		unit   string
+		want   string
+	}{
+		{1.5e9, 3, "Hz", "1.5GHz"},
+		{0.000012, 3, "s", "12µs"},
+		{0.0125, 3, "s", "12.5ms"},
+		{3210, 2, " ops/s", "3.2k ops/s"},
+		{999.95, 3, "", "1k"},
+		{-4.5e-9, 3, "s", "-4.5ns"},
+		{42, 3, "", "42"},
+		{0, 3, "B", "0B"},
+		{math.Inf(1), 3, "", "+Inf"},
+		{1.79e308, 3, "Hz", "1.79e+308Hz"},
+		{-2e21, 2, "B", "-2.0e+21B"},
+		{1e-15, 3, "Hz", "1.00e-15Hz"},
+		{5e-324, 3, "Hz", "4.94e-324Hz"},
+	}
+	for _, tt := range tests {
+		if got := string(appendSI(nil, tt.v, tt.digits, tt.unit)); got != tt.want {
+			t.Errorf("%g: expected %q, got %q", tt.v, tt.want, got)
+		}
+	}
+
+	reg := NewRegistry[testFile]()
+	reg.Field("size", "Size", "Size in bytes").
+		Width(8).
+		Align(AlignRight).
+		Size("iec", func(f *testFile) uint64 { return uint64(f.Size) }).
+		Register()
+	reg.Field("rate", "Rate", "Reads per second").
+		Width(10).
+		Scaled(" reads/s", func(f *testFile) float64 { return float64(f.Size) / 10 }).
+		Register()
+	reg.Field("raw", "Raw", "Size as a float").
+		Float(2, func(f *testFile) float64 { return float64(f.Size) }).
+		Register()
+
+	file := testFile{Size: 1536 << 20}
+	for spec, want := range map[string]string{
+		"size":           "1.5Gi",
+		"size:%si-long":  "1.61 GB",
+		"size:.4:%si":    "1.611G",
+		"size:%binary@M": "1536M",
+		"rate:12":        "161M reads/s",
+		"raw:.3:%si":     "1.61G",
+	} {
+		prog, err := CompileWithOptions(reg, spec, Options{Format: FormatCSV})
+		if err != nil {
+			t.Errorf("%s: compile failed: %v", spec, err)
+			continue
+		}
+		var tmp, line []byte
+		if got := prog.FormatRow(&file, &tmp, &line); got != want {
+			t.Errorf("%s: expected %q, got %q", spec, want, got)
+		}
+	}
+
+	if _, err := Compile(reg, "size:%e"); err == nil || !strings.Contains(err.Error(), "%iec@M") {
+		t.Errorf("expected an error for a float format, got %v", err)
+	}
+}
+
+func TestNumberFormat(t *testing.T) {
+	acct := NumberEN
+	acct.Accounting = true
+	tests := []struct {
+		nf   NumberFormat
+		num  string
+		want string
+	}{
+		{NumberFormat{}, "-1234567.5", "-1234567.5"},
+		{NumberEN, "1234567", "1,234,567"},
+		{NumberEN, "123", "123"},
+		{NumberEN, "-123456.25", "-123,456.25"},
+		{NumberDE, "1234567.89", "1.234.567,89"},
+		{NumberFR, "1234.5", "1\u202f234,5"},
+		{NumberIN, "1234567890", "1,23,45,67,890"},
+		{NumberIN, "12345", "12,345"},
+		{NumberIN, "999", "999"},
+		{acct, "-1234.50", "(1,234.50)"},
+		{acct, "1234.50", "1,234.50"},
+		{NumberDE, "1.5e+06", "1,5e+06"},
+		{acct, "-Inf", "(Inf)"},
+		{NumberEN, "NaN", "NaN"},
+	}
+	for _, tt := range tests {
+		dst := append([]byte("x="), tt.num...)
+		if got := string(tt.nf.apply(dst, 2)); got != "x="+tt.want {
+			t.Errorf("%+v of %q: expected %q, got %q", tt.nf, tt.num, "x="+tt.want, got)
+		}
+	}
+}
+
+type testLedger struct {
+	Count  int
+	Amount float64
+}
+
+func TestNumberFormatFields(t *testing.T) {
+	reg := NewRegistry[testLedger]()
+	reg.Field("count", "Count", "Entries").
+		Width(12).
+		Align(AlignRight).
+		Int(func(l *testLedger) int { return l.Count }).
+		Register()
+	reg.Field("amount", "Amount", "Balance").
+		Width(14).
+		Float(2, func(l *testLedger) float64 { return l.Amount }).
+		Register()
+	reg.Field("code", "Code", "Account code").
+		Width(6).
+		Int(func(l *testLedger) int { return l.Count }).
+		Format("%x").
+		Number(NumberEN).
+		Register()
+
+	row := testLedger{Count: 1234567, Amount: -9876.5}
+	tests := []struct {
+		spec string
+		opts Options
+		want string
+	}{
+		{"count,amount", Options{}, "1234567,-9876.50"},
+		{"count,amount", Options{Number: NumberEN}, `"1,234,567","-9,876.50"`},
+		{"count:de,amount:de:acct", Options{}, `1.234.567,"(9.876,50)"`},
+		{"count:in,amount:acct", Options{Number: NumberDE}, `"12,34,567","(9.876,50)"`},
+		{"code", Options{Number: NumberDE}, "12d687"},
+		{"count:%09d", Options{Number: NumberEN}, "001234567"},
+		{"count:%09d", Options{Number: NumberFormat{Group: ",", Accounting: true}}, "001234567"},
+	}
+	var tmp, line []byte
+	for _, tt := range tests {
+		tt.opts.Format = FormatCSV
+		prog, err := CompileWithOptions(reg, tt.spec, tt.opts)
+		if err != nil {
+			t.Fatalf("%q: compile failed: %v", tt.spec, err)
+		}
+		if got := prog.FormatRow(&row, &tmp, &line); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.spec, tt.want, got)
+		}
+	}
+
+	// Zero padding and an explicit grouping conflict
+	for _, spec := range []string{"count:de:%09d", "count:en:acct:%09d"} {
+		if _, err := Compile(reg, spec); err == nil || !strings.Contains(err.Error(), "pads with zeros") {
+			t.Errorf("%q: expected a zero padding error, got %v", spec, err)
+		}
+	}
+
+	// The keywords select copies of the presets
+	saved := NumberDE
+	NumberDE.Group = "'"
+	prog, err := CompileWithOptions(reg, "count:de", Options{Format: FormatCSV})
+	NumberDE = saved
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	if got := prog.FormatRow(&row, &tmp, &line); got != "1.234.567" {
+		t.Errorf("expected the de keyword to ignore changes to NumberDE, got %q", got)
+	}
+
+	// AlignDecimal finds the locale's decimal point past its group marks
+	prog, err = CompileWithOptions(reg, "amount:=:de", Options{NoHeader: true, NoUnderline: true, PadLastColumn: true})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	var buf bytes.Buffer
+	for _, l := range []testLedger{{Amount: 1234.5}, {Amount: 3}} {
+		prog.WriteRow(&buf, &l, &tmp, &line)
+	}
+	if got, want := buf.String(), "      1.234,50\n          3,00\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	if got, want := prog.Spec(), "amount:=:de"; got != want {
+		t.Errorf("expected spec %q, got %q", want, got)
+	}
+
+	// Accounting reserves a cell for the ')', so the points still line up
+	prog, err = CompileWithOptions(reg, "amount:=12:en:acct", Options{NoHeader: true, NoUnderline: true, PadLastColumn: true})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	buf.Reset()
+	for _, l := range []testLedger{{Amount: 7}, {Amount: -7}, {Amount: 1234.5}, {Amount: -1234.5}} {
+		prog.WriteRow(&buf, &l, &tmp, &line)
+	}
+	if got, want := buf.String(), "       7.00 \n      (7.00)\n   1,234.50 \n  (1,234.50)\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if _, err := Compile(reg, "count:wrap:fr"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	reg.Field("name", "Name", "Account name").
+		String(func(l *testLedger) string { return "" }).
+		Register()
+	if _, err := Compile(reg, "name:en"); err == nil || !strings.Contains(err.Error(), "not a number") {
+		t.Errorf("expected a number format error, got %v", err)
+	}
+}
+
+func TestFloatFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		prec   int
+		v      float64
+		want   string
+	}{
+		{"", 2, 3.14159, "3.14"},
+		{"%f", 0, 2.5, "2"},
+		{"%e", 2, 123456, "1.23e+05"},
+		{"%e", 1, 1.5e-9, "1.5e-09"},
+		{"%eng", 2, 123456, "123.46e+03"},
+		{"%eng", 2, 1.5e-9, "1.50e-09"},
+		{"%eng", 1, 0.00042, "420.0e-06"},
+		{"%eng", 0, -12345, "-12e+03"},
+		{"%eng", 1, 999.96, "1.0e+03"},
+		{"%eng", 1, 99.96, "100.0e+00"},
+		{"%eng", 2, 0, "0.00e+00"},
+		{"%g", 2, 0.1, "0.1"},
+		{"%g", 2, 1.0 / 3, "0.3333333333333333"},
+		{"%g", 2, 1e21, "1e+21"},
+		{"%sig", 3, 3.14159, "3.14"},
+		{"%sig", 3, 1.5, "1.50"},
+		{"%sig", 3, 0.000123456, "0.000123"},
+		{"%sig", 3, 0.0000123456, "1.23e-05"},
+		{"%sig", 3, 999.6, "1.00e+03"},
+		{"%sig", 3, 99.96, "100"},
+		{"%sig", 3, -42, "-42.0"},
+		{"%sig", 0, 7.7, "8"},
+		{"%e", 2, math.NaN(), "NaN"},
+		{"%eng", 2, math.Inf(-1), "-Inf"},
+		{"%sig", 3, math.Inf(1), "+Inf"},
+	}
+	for _, tt := range tests {
+		ff := floatFormat{style: floatStyles[tt.format], prec: tt.prec}
+		dst := append([]byte(nil), "x="...)
+		if got := string(ff.append(dst, tt.v)); got != "x="+tt.want {
+			t.Errorf("%q.%d of %g: expected %q, got %q", tt.format, tt.prec, tt.v, "x="+tt.want, got)
+		}
+	}
+}
+
+func TestFloatFields(t *testing.T) {
+	reg := NewRegistry[testLedger]()
+	reg.Field("amount", "Amount", "Balance").
+		Width(12).
+		Float(2, func(l *testLedger) float64 { return l.Amount }).
+		Register()
+
+	opts := Options{
+		Format:    FormatCSV,
+		NonFinite: NonFinite{NaN: "n/a", PosInf: "∞", NegInf: "-∞"},
+	}
+	values := []float64{12345.678, 0.25, math.NaN(), math.Inf(1), math.Inf(-1)}
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"amount:%eng", []string{"12.35e+03", "250.00e-03", "n/a", "∞", "-∞"}},
+		{"amount:.4:%sig", []string{"1.235e+04", "0.2500", "n/a", "∞", "-∞"}},
+		{"amount:%g", []string{"12345.678", "0.25", "n/a", "∞", "-∞"}},
+	}
+	var tmp, line []byte
+	for _, tt := range tests {
+		prog, err := CompileWithOptions(reg, tt.spec, opts)
+		if err != nil {
+			t.Fatalf("%q: compile failed: %v", tt.spec, err)
+		}
+		if got := prog.Spec(); got != tt.spec {
+			t.Errorf("expected spec %q, got %q", tt.spec, got)
+		}
+		for i, v := range values {
+			row := testLedger{Amount: v}
+			if got := prog.FormatRow(&row, &tmp, &line); got != tt.want[i] {
+				t.Errorf("%q of %g: expected %q, got %q", tt.spec, v, tt.want[i], got)
+			}
+		}
+	}
+
+	// AlignDecimal leaves room for the exponent
+	prog, _ := CompileWithOptions(reg, "amount:=:%e", Options{NoHeader: true, NoUnderline: true, PadLastColumn: true})
+	var buf bytes.Buffer
+	for _, l := range []testLedger{{Amount: 1.5}, {Amount: -2.5e-10}} {
+		prog.WriteRow(&buf, &l, &tmp, &line)
+	}
+	if got, want := buf.String(), "    1.50e+00\n   -2.50e-10\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	if _, err := Compile(reg, "amount:%x"); err == nil || !strings.Contains(err.Error(), "%eng") {
+		t.Errorf("expected an error listing the float formats, got %v", err)
+	}
+}
+
 func TestFormatString(t *testing.T) {
 	reg := NewRegistry[testPerson]()
 
 	reg.Field("name", "Name", "Test").
-		Width(10).
+		Width(10).
+		String((*testPerson).GetName).
+		Register()
+
+	prog, _ := Compile(reg, "name")
+
+	person := testPerson{Name: "Alice"}
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+
+	result := prog.FormatRow(&person, &tmp, &line)
+
+	expected := "Alice" // No padding on last column
+	if result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestFormatInt(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("age", "Age", "Test").
+		Width(5).
+		Int((*testPerson).GetAge).
+		Register()
+
+	prog, _ := Compile(reg, "age")
+
+	person := testPerson{Age: 42}
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+
+	result := prog.FormatRow(&person, &tmp, &line)
+
+	expected := "42" // No padding on last column
+	if result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("temp", "Temp", "Test").
+		Width(8).
+		Float(2, (*testPerson).GetTemp).
+		Register()
+
+	prog, _ := Compile(reg, "temp")
+
+	person := testPerson{Temp: 98.6}
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+
+	result := prog.FormatRow(&person, &tmp, &line)
+
+	expected := "98.60" // No padding on last column
+	if result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestFormatCustom(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("custom", "Custom", "Test").
+		Width(10).
+		Custom(func(dst []byte, p *testPerson) []byte {
+			return append(dst, "CUSTOM"...)
+		}).
+		Register()
+
+	prog, _ := Compile(reg, "custom")
+
+	person := testPerson{}
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+
+	result := prog.FormatRow(&person, &tmp, &line)
+
+	expected := "CUSTOM" // No padding on last column
+	if result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestHeaderAndUnderline(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		Width(10).
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("age", "Age", "Test").
+		Width(5).
+		Int((*testPerson).GetAge).
+		Register()
+
+	prog, _ := Compile(reg, "name,age")
+
+	var buf bytes.Buffer
+	line := make([]byte, 0, 64)
+
+	prog.WriteHeader(&buf, &line)
+	header := buf.String()
+
+	expectedHeader := "Name        Age\n"
+	if header != expectedHeader {
+		t.Errorf("expected header %q, got %q", expectedHeader, header)
+	}
+
+	buf.Reset()
+	prog.WriteUnderline(&buf, &line)
+	underline := buf.String()
+
+	expectedUnderline := "----        ---\n"
+	if underline != expectedUnderline {
+		t.Errorf("expected underline %q, got %q", expectedUnderline, underline)
+	}
+}
+
+func TestCustomSeparator(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		Width(5).
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("age", "Age", "Test").
+		Width(3).
+		Int((*testPerson).GetAge).
+		Register()
+
+	opts := Options{Separator: ","}
+	prog, _ := CompileWithOptions(reg, "name,age", opts)
+
+	person := testPerson{Name: "Bob", Age: 25}
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+
+	result := prog.FormatRow(&person, &tmp, &line)
+
+	expected := "Bob  ,25"
+	if result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestTruncation(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		Width(5). // Only 5 chars
+		String((*testPerson).GetName).
+		Register()
+
+	prog, _ := Compile(reg, "name")
+
+	person := testPerson{Name: "Alexander"} // 9 chars
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+
+	result := prog.FormatRow(&person, &tmp, &line)
+
+	expected := "Alexa" // Truncated to 5
+	if result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestAlignment(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		Width(7).
+		Align(AlignCenter).
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("age", "Age", "Test").
+		Width(5).
+		Align(AlignRight).
+		Int((*testPerson).GetAge).
+		Register()
+
+	reg.Field("temp", "Temp", "Test").
+		Width(8).
+		Align(AlignDecimal).
+		Float(1, (*testPerson).GetTemp).
+		Register()
+
+	prog, err := CompileWithOptions(reg, "name,age,temp", Options{Separator: "|", PadLastColumn: true})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	if got, want := prog.HeaderString(), " Name  |  Age|    Temp"; got != want {
+		t.Errorf("expected header %q, got %q", want, got)
+	}
+
+	person := testPerson{Name: "Bob", Age: 42, Temp: 98.6}
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+
+	if got, want := prog.FormatRow(&person, &tmp, &line), "  Bob  |   42|    98.6"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestDecimalAlignment(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	// Custom values with varying precision still line up on the '.'
+	reg.Field("val", "Val", "Test").
+		Width(8).
+		Align(AlignDecimal).
+		Precision(3).
+		Custom(func(dst []byte, p *testPerson) []byte {
+			return append(dst, p.Name...)
+		}).
+		Register()
+
+	prog, _ := CompileWithOptions(reg, "val", Options{PadLastColumn: true})
+
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+
+	for _, tc := range []struct{ in, want string }{
+		{"1.5", "   1.5  "},
+		{"10.25", "  10.25 "},
+		{"100", " 100    "},
+		{"0.125", "   0.125"},
+	} {
+		person := testPerson{Name: tc.in}
+		if got := prog.FormatRow(&person, &tmp, &line); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestSpecAlignOverride(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		Width(10).
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("age", "Age", "Test").
+		Width(5).
+		Int((*testPerson).GetAge).
+		Register()
+
+	prog, err := Compile(reg, "name:>8,age:^")
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	if prog.columns[0].width != 8 || prog.columns[0].align != AlignRight {
+		t.Errorf("unexpected name column: width %d, align %d", prog.columns[0].width, prog.columns[0].align)
+	}
+	if prog.columns[1].width != 5 || prog.columns[1].align != AlignCenter {
+		t.Errorf("unexpected age column: width %d, align %d", prog.columns[1].width, prog.columns[1].align)
+	}
+
+	person := testPerson{Name: "Bob", Age: 7}
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+
+	// Trailing padding is still trimmed on a centered last column
+	if got, want := prog.FormatRow(&person, &tmp, &line), "     Bob    7"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	if _, err := Compile(reg, "name:>x"); err == nil {
+		t.Error("expected error for invalid width after alignment")
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"hello", 5},
+		{"", 0},
+		{"café", 4},
+		{"cafe\u0301", 4},           // e + combining acute
+		{"日本語", 6},                  // CJK wide
+		{"ｈｉ", 4},                   // fullwidth Latin
+		{"한국", 4},                   // Hangul syllables
+		{"\U0001F600", 2},           // 😀
+		{"\U0001F44D\U0001F3FD", 2}, // thumbs up + skin tone
+		{"\U0001F468\u200D\U0001F469\u200D\U0001F467", 2}, // family ZWJ sequence
+		{"\U0001F1EF\U0001F1F5", 2},                       // flag: JP
+		{"\u2764\uFE0F", 2},                               // heart with emoji presentation
+		{"1\uFE0F\u20E3", 2},                              // keycap one
+		{"a\u200Bb", 2},                                   // zero width space
+	}
+	for _, tc := range tests {
+		if got := textWidth(tc.in); got != tc.want {
+			t.Errorf("textWidth(%q) = %d, want %d", tc.in, got, tc.want)
+		}
+		if got := textWidth([]byte(tc.in)); got != tc.want {
+			t.Errorf("textWidth([]byte(%q)) = %d, want %d", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestUnicodePadding(t *testing.T) {
+	tests := []struct {
+		in    string
+		width int
+		align Align
+		want  string
+	}{
+		{"café", 6, AlignLeft, "café  "},
+		{"日本", 6, AlignRight, "  日本"},
+		{"日本語", 5, AlignLeft, "日本 "},                // wide char does not straddle the edge
+		{"cafe\u0301s", 4, AlignLeft, "cafe\u0301"}, // accent stays with its letter
+		{"\U0001F468\u200D\U0001F469 x", 3, AlignLeft, "\U0001F468\u200D\U0001F469 "},
+		{"ab\U0001F1EF\U0001F1F5", 3, AlignLeft, "ab "}, // flag is not split
+	}
+	for _, tc := range tests {
+		c := cell{width: tc.width, align: tc.align}
+		got := string(padCell(nil, tc.in, &c))
+		if got != tc.want {
+			t.Errorf("padCell(%q, %d) = %q, want %q", tc.in, tc.width, got, tc.want)
+		}
+	}
+}
+
+func TestUnicodeHeaderAndUnderline(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "名前", "Test").
+		Width(6).
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("age", "Âge", "Test").
+		Width(4).
+		Int((*testPerson).GetAge).
+		Register()
+
+	prog, _ := Compile(reg, "name,age")
+
+	var buf bytes.Buffer
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+
+	prog.WriteHeader(&buf, &line)
+	prog.WriteUnderline(&buf, &line)
+	person := testPerson{Name: "José", Age: 30}
+	prog.WriteRow(&buf, &person, &tmp, &line)
+
+	expected := "名前    Âge\n----    ---\nJosé    30\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestTruncateStrategies(t *testing.T) {
+	tests := []struct {
+		trunc Truncate
+		in    string
+		want  string
+	}{
+		{TruncateCut, "abcdefghij", "abcdef"},
+		{TruncateEllipsis, "abcdefghij", "abcde…"},
+		{TruncateLeading, "/usr/local/bin", "…l/bin"},
+		{TruncateMiddle, "0123456789", "012…89"},
+		{TruncateOverflow, "abcdefghij", "abcdefghij"},
+		{TruncateHash, "1234567890", "######"},
+		{TruncateEllipsis, "short", "short "},
+		{TruncateEllipsis, "日本語テキスト", "日本… "}, // wide chars do not straddle the ellipsis
+		{TruncateLeading, "日本語テキスト", "…スト "},
+	}
+	for _, tc := range tests {
+		c := cell{width: 6, trunc: tc.trunc}
+		if got := string(padCell(nil, tc.in, &c)); got != tc.want {
+			t.Errorf("truncate %d of %q = %q, want %q", tc.trunc, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestSpecTruncateOverride(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		Width(10).
+		Truncate(TruncateEllipsis).
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("age", "Age", "Test").
+		Width(2).
+		Truncate(TruncateHash).
+		Int((*testPerson).GetAge).
+		Register()
+
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+	person := testPerson{Name: "Maximilian", Age: 123}
+
+	prog, err := Compile(reg, "name:6,age")
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	if got, want := prog.FormatRow(&person, &tmp, &line), "Maxim…  ##"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	prog, err = Compile(reg, "name:>6:middle,age:overflow")
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	if got, want := prog.FormatRow(&person, &tmp, &line), "Max…an  123"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// Headers are never replaced by hash marks
+	if got, want := prog.HeaderString(), "  Name  Ag"; got != want {
+		t.Errorf("expected header %q, got %q", want, got)
+	}
+}
+
+func TestWrapSegment(t *testing.T) {
+	tests := []struct {
+		in    string
+		width int
+		want  []string
+	}{
+		{"short", 10, []string{"short"}},
+		{"the quick brown fox", 10, []string{"the quick", "brown fox"}},
+		{"supercalifragilistic", 8, []string{"supercal", "ifragili", "stic"}},
+		{"one\ntwo three", 5, []string{"one", "two", "three"}},
+		{"a  b", 1, []string{"a", "b"}},
+		{"日本語のテキスト", 5, []string{"日本", "語の", "テキ", "スト"}},
+	}
+	for _, tc := range tests {
+		s := []byte(tc.in)
+		var got []string
+		for len(s) > 0 {
+			end, next := wrapSegment(s, tc.width)
+			got = append(got, string(s[:end]))
+			s = s[next:]
+		}
+		if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+			t.Errorf("wrap %q at %d = %q, want %q", tc.in, tc.width, got, tc.want)
+		}
+	}
+}
+
+func TestWrapRow(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("age", "Age", "Test").
+		Width(3).
+		Align(AlignRight).
+		Int((*testPerson).GetAge).
+		Register()
+
+	reg.Field("name", "Name", "Test").
+		Width(8).
+		Wrap().
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("temp", "Temp", "Test").
+		Width(5).
+		Float(1, (*testPerson).GetTemp).
+		Register()
+
+	prog, err := Compile(reg, "age,name,temp")
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+
+	person := testPerson{Name: "Jean-Baptiste Emmanuel Zorg", Age: 42, Temp: 36.6}
+	prog.WriteRow(&buf, &person, &tmp, &line)
+	person = testPerson{Name: "Bob", Age: 7, Temp: 37}
+	prog.WriteRow(&buf, &person, &tmp, &line)
+
+	expected := " 42  Jean-Bap  36.6\n" +
+		"     tiste\n" +
+		"     Emmanuel\n" +
+		"     Zorg\n" +
+		"  7  Bob       37.0\n"
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	// Wrapping can also be requested from the spec
+	prog, err = Compile(reg, "temp,age:wrap")
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	if prog.wrapCols != 1 || !prog.columns[1].wrap {
+		t.Error("expected age column to wrap")
+	}
+}
+
+func TestAutoWidthFit(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		MaxWidth(6).
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("age", "Age", "Test").
+		Int((*testPerson).GetAge).
+		Register()
+
+	reg.Field("temp", "Temperature", "Test").
+		MinWidth(8).
+		Float(1, (*testPerson).GetTemp).
+		Register()
+
+	reg.Field("years", "Age", "Test").
+		Int((*testPerson).GetAge).
+		Register()
+
+	people := []testPerson{
+		{Name: "Al", Age: 7, Temp: 36.6},
+		{Name: "Bartholomew", Age: 101, Temp: 37.2},
+	}
+
+	prog, err := CompileWithOptions(reg, "name,age,temp,years:2", Options{Separator: " ", AutoWidth: true})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	prog.Fit(people)
+
+	// name is capped at 6, age fits "101", temp keeps its full header,
+	// and the pinned years:2 still fits its header
+	widths := []int{6, 3, 11, 3}
+	for i, w := range widths {
+		if prog.columns[i].width != w {
+			t.Errorf("column %d: expected width %d, got %d", i, w, prog.columns[i].width)
+		}
+	}
+
+	if got, want := prog.HeaderString(), "Name   Age Temperature Age"; got != want {
+		t.Errorf("expected header %q, got %q", want, got)
+	}
+}
+
+func TestAutoWidthStream(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("age", "Age", "Test").
+		Int((*testPerson).GetAge).
+		Register()
+
+	prog, _ := CompileWithOptions(reg, "name,age", Options{
+		Separator:  " ",
+		AutoWidth:  true,
+		SampleRows: 2,
+	})
+
+	var buf bytes.Buffer
+	st := prog.Stream(&buf)
+
+	st.WriteRow(&testPerson{Name: "Alice", Age: 30})
+	if buf.Len() != 0 {
+		t.Fatalf("expected rows to be held back while sampling, got %q", buf.String())
+	}
+	st.WriteRow(&testPerson{Name: "Bob", Age: 4})
+	st.WriteRow(&testPerson{Name: "Christopher", Age: 55}) // after the widths lock
+	st.Flush()
+
+	expected := "Name  Age\n" +
+		"----  ---\n" +
+		"Alice 30\n" +
+		"Bob   4\n" +
+		"Chris 55\n"
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	// Short streams are flushed explicitly
+	buf.Reset()
+	prog, _ = CompileWithOptions(reg, "name,age", Options{Separator: " ", AutoWidth: true, NoUnderline: true})
+	st = prog.Stream(&buf)
+	st.WriteRow(&testPerson{Name: "Christopher", Age: 55})
+	st.Flush()
+	if got, want := buf.String(), "Name        Age\nChristopher 55\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestMaxLineWidth(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		Width(20).
+		Priority(10).
+		Shrinkable().
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("age", "Age", "Test").
+		Width(5).
+		Priority(5).
+		Int((*testPerson).GetAge).
+		Register()
+
+	reg.Field("temp", "Temp", "Test").
+		Width(8).
+		Float(1, (*testPerson).GetTemp).
+		Register()
+
+	tests := []struct {
+		maxWidth int
+		widths   []int
+		dropped  []string
+	}{
+		{0, []int{20, 5, 8}, nil},           // no limit
+		{40, []int{20, 5, 8}, nil},          // 20+5+8 plus two separators fits
+		{30, []int{13, 5, 8}, nil},          // name shrinks
+		{16, []int{9, 5}, []string{"temp"}}, // temp has the lowest priority
+		{6, []int{6}, []string{"temp", "age"}},
+		{2, []int{4}, []string{"temp", "age"}}, // the last column is kept
+	}
+	for _, tc := range tests {
+		prog, err := CompileWithOptions(reg, "name,age,temp", Options{Separator: "  ", MaxLineWidth: tc.maxWidth})
+		if err != nil {
+			t.Fatalf("compile failed: %v", err)
+		}
+		var widths []int
+		for _, col := range prog.columns {
+			widths = append(widths, col.width)
+		}
+		if fmt.Sprint(widths) != fmt.Sprint(tc.widths) {
+			t.Errorf("max %d: expected widths %v, got %v", tc.maxWidth, tc.widths, widths)
+		}
+		if fmt.Sprint(prog.Dropped()) != fmt.Sprint(tc.dropped) {
+			t.Errorf("max %d: expected dropped %v, got %v", tc.maxWidth, tc.dropped, prog.Dropped())
+		}
+	}
+}
+
+func TestFitTerminalColumnsEnv(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		Width(20).
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("age", "Age", "Test").
+		Width(5).
+		Int((*testPerson).GetAge).
+		Register()
+
+	if _, ok := ttyWidth(os.Stdout.Fd()); ok {
+		t.Skip("stdout is a terminal")
+	}
+
+	// Without a terminal, $COLUMNS decides
+	t.Setenv("COLUMNS", "22")
+	prog, _ := CompileWithOptions(reg, "name,age", Options{Separator: "  ", FitTerminal: true})
+	if got := prog.Dropped(); len(got) != 1 || got[0] != "age" {
+		t.Errorf("expected age to be dropped, got %v", got)
+	}
+
+	t.Setenv("COLUMNS", "")
+	prog, _ = CompileWithOptions(reg, "name,age", Options{Separator: "  ", FitTerminal: true})
+	if len(prog.columns) != 2 {
+		t.Errorf("expected no limit without a known width, got %d columns", len(prog.columns))
+	}
+}
+
+func TestCSVFormat(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Full Name", "Test").
+		Width(3).
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("age", "Age", "Test").
+		Width(2).
+		Int((*testPerson).GetAge).
+		Register()
+
+	prog, err := CompileWithOptions(reg, "name,age", Options{Format: FormatCSV, CSV: CSVOptions{BOM: true}})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+
+	prog.WriteHeader(&buf, &line)
+	prog.WriteUnderline(&buf, &line)
+	for _, p := range []testPerson{
+		{Name: "Plain", Age: 100},
+		{Name: "a,b", Age: 1},
+		{Name: `say "hi"`, Age: 2},
+		{Name: "two\nlines", Age: 3},
+		{Name: " padded", Age: 4},
+		{Name: "", Age: 5},
+	} {
+		prog.WriteRow(&buf, &p, &tmp, &line)
+	}
+
+	expected := "\uFEFFFull Name,Age\r\n" +
+		"Plain,100\r\n" +
+		"\"a,b\",1\r\n" +
+		"\"say \"\"hi\"\"\",2\r\n" +
+		"\"two\nlines\",3\r\n" +
+		"\" padded\",4\r\n" +
+		",5\r\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestTSVFormat(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("temp", "Temp", "Test").
+		Float(1, (*testPerson).GetTemp).
+		Register()
+
+	prog, _ := CompileWithOptions(reg, "name,temp", Options{
+		Format: FormatTSV,
+		CSV:    CSVOptions{Quote: '\'', LineTerminator: "\n", HeaderNames: true},
+	})
+
+	if got, want := prog.HeaderString(), "name\ttemp"; got != want {
+		t.Errorf("expected header %q, got %q", want, got)
+	}
+
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+	person := testPerson{Name: "it's\there", Temp: 36.6}
+	if got, want := prog.FormatRow(&person, &tmp, &line), "'it''s\there'\t36.6"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Full Name", "Test").
+		Width(3).
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("age", "Age", "Test").
+		Int((*testPerson).GetAge).
+		Register()
+
+	reg.Field("temp", "Temp", "Test").
+		Float(1, (*testPerson).GetTemp).
+		Register()
+
+	prog, err := CompileWithOptions(reg, "name,age,temp", Options{Format: FormatJSONLines})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	line := make([]byte, 0, 64)
+	tmp := make([]byte, 0, 32)
+
+	prog.WriteHeader(&buf, &line)
+	prog.WriteUnderline(&buf, &line)
+	for _, p := range []testPerson{
+		{Name: "Alice", Age: 30, Temp: 98.6},
+		{Name: "say \"hi\"\n\\\x01\u2028", Age: -1, Temp: math.NaN()},
+		{Name: "bad\xffutf8", Temp: 1e-7},
+	} {
+		prog.WriteRow(&buf, &p, &tmp, &line)
+	}
+	prog.WriteFooter(&buf, &line)
+
+	expected := `{"name":"Alice","age":30,"temp":98.6}` + "\n" +
+		`{"name":"say \"hi\"\n\\\u0001\u2028","age":-1,"temp":null}` + "\n" +
+		`{"name":"bad\ufffdutf8","age":0,"temp":1e-7}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestJSONArray(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("raw", "Raw", "Test").
+		Custom(func(dst []byte, p *testPerson) []byte {
+			if p.Age == 0 {
+				return dst
+			}
+			dst = append(dst, `{"age":`...)
+			dst = strconv.AppendInt(dst, int64(p.Age), 10)
+			return append(dst, '}')
+		}).
+		RawJSON().
+		Register()
+
+	reg.Field("quoted", "Quoted", "Test").
+		Custom(func(dst []byte, p *testPerson) []byte {
+			return append(dst, `a"b`...)
+		}).
+		Register()
+
+	prog, err := CompileWithOptions(reg, "name,raw,quoted", Options{Format: FormatJSON})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	s := prog.Stream(&buf)
+	s.WriteRow(&testPerson{Name: "Alice", Age: 30})
+	s.WriteRow(&testPerson{Name: "Bob"})
+	if err := s.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	expected := "[\n" +
+		`{"name":"Alice","raw":{"age":30},"quoted":"a\"b"},` + "\n" +
+		`{"name":"Bob","raw":null,"quoted":"a\"b"}` + "\n" +
+		"]\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	// An empty array
+	buf.Reset()
+	s = prog.Stream(&buf)
+	s.Close()
+	if got, want := buf.String(), "[\n]\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestMarkdownFormat(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		Width(5).
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("age", "Age", "Test").
+		Width(3).
+		Align(AlignRight).
+		Int((*testPerson).GetAge).
+		Register()
+
+	reg.Field("temp", "Temp", "Test").
+		Width(4).
+		Align(AlignCenter).
+		Float(1, (*testPerson).GetTemp).
+		Register()
+
+	people := []testPerson{
+		{Name: "Alice", Age: 30, Temp: 36.6},
+		{Name: `a|b\c`, Age: 5, Temp: 1},
+		{Name: "Bartholomew", Age: 101, Temp: 40},
+	}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{
+			name: "padded",
+			opts: Options{Format: FormatMarkdown},
+			expected: "| Name  | Age | Temp |\n" +
+				"|-------|----:|:----:|\n" +
+				"| Alice |  30 | 36.6 |\n" +
+				"| a\\|b\\\\c |   5 | 1.0  |\n" +
+				"| Bartholomew | 101 | 40.0 |\n",
+		},
+		{
+			name: "compact",
+			opts: Options{Format: FormatMarkdown, NoPadding: true},
+			expected: "| Name | Age | Temp |\n" +
+				"|---|---:|:---:|\n" +
+				"| Alice | 30 | 36.6 |\n" +
+				"| a\\|b\\\\c | 5 | 1.0 |\n" +
+				"| Bartholomew | 101 | 40.0 |\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := CompileWithOptions(reg, "name,age,temp", tt.opts)
+			if err != nil {
+				t.Fatalf("compile failed: %v", err)
+			}
+
+			var buf bytes.Buffer
+			line := make([]byte, 0, 64)
+			tmp := make([]byte, 0, 32)
+
+			prog.WriteHeader(&buf, &line)
+			prog.WriteUnderline(&buf, &line)
+			for i := range people {
+				prog.WriteRow(&buf, &people[i], &tmp, &line)
+			}
+
+			if buf.String() != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestHTMLFormat(t *testing.T) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Full <name>").
+		String((*testPerson).GetName).
+		Register()
+
+	reg.Field("Age", "Age", "").
+		Int((*testPerson).GetAge).
+		Register()
+
+	reg.Field("temp%", "T & C", "Test").
+		Align(AlignCenter).
+		Float(1, (*testPerson).GetTemp).
+		Register()
+
+	prog, err := CompileWithOptions(reg, "name,age,temp%", Options{Format: FormatHTML})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	s := prog.Stream(&buf)
+	s.WriteRow(&testPerson{Name: `<b>"Al" & 'Bo'</b>`, Age: 30, Temp: 36.6})
+	if err := s.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	expected := "<table>\n" +
+		"<thead>\n" +
+		`<tr><th class="col-name" title="Full &lt;name&gt;">Name</th>` +
+		`<th class="col-age" style="text-align:right">Age</th>` +
+		`<th class="col-temp-" style="text-align:center" title="Test">T &amp; C</th></tr>` + "\n" +
+		"</thead>\n" +
+		"<tbody>\n" +
+		`<tr><td class="col-name">&lt;b&gt;&#34;Al&#34; &amp; &#39;Bo&#39;&lt;/b&gt;</td>` +
+		`<td class="col-age" style="text-align:right">30</td>` +
+		`<td class="col-temp-" style="text-align:center">36.6</td></tr>` + "\n" +
+		"</tbody>\n" +
+		"</table>\n"
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+// Benchmark the hot path - formatting rows
+func BenchmarkWriteRow(b *testing.B) {
+	reg := NewRegistry[testPerson]()
+
+	reg.Field("name", "Name", "Test").
+		Width(15).
 		String((*testPerson).GetName).
 		Register()
 
-	prog, _ := Compile(reg, "name")
+	reg.Field("age", "Age", "Test").
+		Width(5).
+		Int((*testPerson).GetAge).
+		Register()
 
-	person := testPerson{Name: "Alice"}
-	line := make([]byte, 0, 64)
-	tmp := make([]byte, 0, 32)
+	reg.Field("temp", "Temp", "Test").
+		Width(8).
+		Float(2, (*testPerson).GetTemp).
+		Register()
 
-	result := prog.FormatRow(&person, &tmp, &line)
+	prog, _ := Compile(reg, "name,age,temp")
 
-	expected := "Alice" // No padding on last column
-	if result != expected {
-		t.Errorf("expected %q, got %q", expected, result)
+	person := testPerson{Name: "Alice", Age: 30, Temp: 98.6}
+	line := make([]byte, 0, 256)
+	tmp := make([]byte, 0, 64)
+
+	var buf bytes.Buffer
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		prog.WriteRow(&buf, &person, &tmp, &line)
 	}
 }
 
-func TestFormatInt(t *testing.T) {
+// Benchmark the non-ASCII path of width measurement and truncation
+func BenchmarkWriteRowUnicode(b *testing.B) {
 	reg := NewRegistry[testPerson]()
 
+	reg.Field("name", "Name", "Test").
+		Width(8).
+		String((*testPerson).GetName).
+		Register()
+
 	reg.Field("age", "Age", "Test").
 		Width(5).
 		Int((*testPerson).GetAge).
 		Register()
 
-	prog, _ := Compile(reg, "age")
+	prog, _ := Compile(reg, "name,age")
 
-	person := testPerson{Age: 42}
-	line := make([]byte, 0, 64)
-	tmp := make([]byte, 0, 32)
+	person := testPerson{Name: "Zoë 日本語テキスト \U0001F44D\U0001F3FD", Age: 30}
+	line := make([]byte, 0, 256)
+	tmp := make([]byte, 0, 64)
 
-	result := prog.FormatRow(&person, &tmp, &line)
+	var buf bytes.Buffer
 
-	expected := "42" // No padding on last column
-	if result != expected {
-		t.Errorf("expected %q, got %q", expected, result)
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		prog.WriteRow(&buf, &person, &tmp, &line)
 	}
 }
 
-func TestFormatFloat(t *testing.T) {
+// Benchmark rows that wrap onto several lines
+func BenchmarkWriteRowWrapped(b *testing.B) {
 	reg := NewRegistry[testPerson]()
 
-	reg.Field("temp", "Temp", "Test").
-		Width(8).
-		Float(2, (*testPerson).GetTemp).
+	reg.Field("age", "Age", "Test").
+		Width(5).
+		Int((*testPerson).GetAge).
 		Register()
 
-	prog, _ := Compile(reg, "temp")
+	reg.Field("name", "Name", "Test").
+		Width(12).
+		Wrap().
+		String((*testPerson).GetName).
+		Register()
 
-	person := testPerson{Temp: 98.6}
-	line := make([]byte, 0, 64)
-	tmp := make([]byte, 0, 32)
+	prog, _ := Compile(reg, "age,name")
 
-	result := prog.FormatRow(&person, &tmp, &line)
+	person := testPerson{Name: "a fairly long description that wraps over several lines", Age: 30}
+	line := make([]byte, 0, 256)
+	tmp := make([]byte, 0, 64)
 
-	expected := "98.60" // No padding on last column
-	if result != expected {
-		t.Errorf("expected %q, got %q", expected, result)
+	var buf bytes.Buffer
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		prog.WriteRow(&buf, &person, &tmp, &line)
 	}
 }
 
-func TestFormatCustom(t *testing.T) {
+// Benchmark CSV rows, some of which need quoting
+func BenchmarkWriteRowCSV(b *testing.B) {
 	reg := NewRegistry[testPerson]()
 
-	reg.Field("custom", "Custom", "Test").
-		Width(10).
-		Custom(func(dst []byte, p *testPerson) []byte {
-			return append(dst, "CUSTOM"...)
-		}).
+	reg.Field("name", "Name", "Test").
+		String((*testPerson).GetName).
 		Register()
 
-	prog, _ := Compile(reg, "custom")
+	reg.Field("age", "Age", "Test").
+		Int((*testPerson).GetAge).
+		Register()
 
-	person := testPerson{}
-	line := make([]byte, 0, 64)
-	tmp := make([]byte, 0, 32)
+	reg.Field("temp", "Temp", "Test").
+		Float(2, (*testPerson).GetTemp).
+		Register()
 
-	result := prog.FormatRow(&person, &tmp, &line)
+	prog, _ := CompileWithOptions(reg, "name,age,temp", Options{Format: FormatCSV})
 
-	expected := "CUSTOM" // No padding on last column
-	if result != expected {
-		t.Errorf("expected %q, got %q", expected, result)
+	person := testPerson{Name: "Smith, Alice", Age: 30, Temp: 98.6}
+	line := make([]byte, 0, 256)
+	tmp := make([]byte, 0, 64)
+
+	var buf bytes.Buffer
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		prog.WriteRow(&buf, &person, &tmp, &line)
 	}
 }
 
-func TestHeaderAndUnderline(t *testing.T) {
+func BenchmarkWriteRowJSON(b *testing.B) {
 	reg := NewRegistry[testPerson]()
 
 	reg.Field("name", "Name", "Test").
-		Width(10).
 		String((*testPerson).GetName).
 		Register()
 
 	reg.Field("age", "Age", "Test").
-		Width(5).
 		Int((*testPerson).GetAge).
 		Register()
 
-	prog, _ := Compile(reg, "name,age")
+	reg.Field("temp", "Temp", "Test").
+		Float(2, (*testPerson).GetTemp).
+		Register()
+
+	prog, _ := CompileWithOptions(reg, "name,age,temp", Options{Format: FormatJSONLines})
+
+	person := testPerson{Name: "Alice \"Al\" Smith", Age: 30, Temp: 98.6}
+	line := make([]byte, 0, 256)
+	tmp := make([]byte, 0, 64)
 
 	var buf bytes.Buffer
-	line := make([]byte, 0, 64)
 
-	prog.WriteHeader(&buf, &line)
-	header := buf.String()
+	b.ResetTimer()
+	b.ReportAllocs()
 
-	expectedHeader := "Name        Age\n"
-	if header != expectedHeader {
-		t.Errorf("expected header %q, got %q", expectedHeader, header)
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		prog.WriteRow(&buf, &person, &tmp, &line)
 	}
+}
 
-	buf.Reset()
-	prog.WriteUnderline(&buf, &line)
-	underline := buf.String()
+func BenchmarkWriteRowTime(b *testing.B) {
+	reg := NewRegistry[testEvent]()
 
-	expectedUnderline := "----        ---\n"
-	if underline != expectedUnderline {
-		t.Errorf("expected underline %q, got %q", expectedUnderline, underline)
+	reg.Field("start", "Start", "Test").
+		Width(19).
+		Time("", func(e *testEvent) time.Time { return e.Start }).
+		In(time.UTC).
+		Register()
+
+	reg.Field("age", "Age", "Test").
+		Time(LayoutRelative, func(e *testEvent) time.Time { return e.Start }).
+		Register()
+
+	reg.Field("took", "Took", "Test").
+		Duration(DurationCompact, func(e *testEvent) time.Duration { return e.Took }).
+		Register()
+
+	prog, _ := Compile(reg, "start,age,took")
+
+	ev := testEvent{Start: time.Now().Add(-time.Hour), Took: 3723 * time.Second}
+	line := make([]byte, 0, 256)
+	tmp := make([]byte, 0, 64)
+
+	var buf bytes.Buffer
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		prog.WriteRow(&buf, &ev, &tmp, &line)
 	}
 }
 
-func TestCustomSeparator(t *testing.T) {
-	reg := NewRegistry[testPerson]()
+func BenchmarkWriteRowHex(b *testing.B) {
+	reg := NewRegistry[testFile]()
 
-	reg.Field("name", "Name", "Test").
-		Width(5).
-		String((*testPerson).GetName).
+	reg.Field("inode", "Inode", "Test").
+		Width(20).
+		Uint64(func(f *testFile) uint64 { return f.Inode }).
+		Format("%#016X").
 		Register()
 
-	reg.Field("age", "Age", "Test").
-		Width(3).
-		Int((*testPerson).GetAge).
+	reg.Field("size", "Size", "Test").
+		Width(20).
+		Align(AlignRight).
+		Int64(func(f *testFile) int64 { return f.Size }).
 		Register()
 
-	opts := Options{Separator: ","}
-	prog, _ := CompileWithOptions(reg, "name,age", opts)
+	reg.Field("mode", "Mode", "Test").
+		Width(6).
+		Int(func(f *testFile) int { return f.Mode }).
+		Format("%#o").
+		Register()
 
-	person := testPerson{Name: "Bob", Age: 25}
-	line := make([]byte, 0, 64)
-	tmp := make([]byte, 0, 32)
+	prog, _ := Compile(reg, "inode,size,mode")
 
-	result := prog.FormatRow(&person, &tmp, &line)
+	file := testFile{Inode: 0xdeadbeef, Size: 1 << 40, Mode: 0644}
+	line := make([]byte, 0, 256)
+	tmp := make([]byte, 0, 64)
 
-	expected := "Bob  ,25"
-	if result != expected {
-		t.Errorf("expected %q, got %q", expected, result)
+	var buf bytes.Buffer
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		prog.WriteRow(&buf, &file, &tmp, &line)
 	}
 }
 
-func TestTruncation(t *testing.T) {
-	reg := NewRegistry[testPerson]()
+func BenchmarkWriteRowSize(b *testing.B) {
+	reg := NewRegistry[testFile]()
 
-	reg.Field("name", "Name", "Test").
-		Width(5). // Only 5 chars
-		String((*testPerson).GetName).
+	reg.Field("size", "Size", "Test").
+		Width(8).
+		Align(AlignRight).
+		Size("iec", func(f *testFile) uint64 { return uint64(f.Size) }).
 		Register()
 
-	prog, _ := Compile(reg, "name")
+	reg.Field("disk", "Disk", "Test").
+		Width(8).
+		Size("si-long", func(f *testFile) uint64 { return f.Inode }).
+		Register()
 
-	person := testPerson{Name: "Alexander"} // 9 chars
-	line := make([]byte, 0, 64)
-	tmp := make([]byte, 0, 32)
+	reg.Field("rate", "Rate", "Test").
+		Width(12).
+		Scaled(" ops/s", func(f *testFile) float64 { return float64(f.Mode) * 1.5 }).
+		Register()
 
-	result := prog.FormatRow(&person, &tmp, &line)
+	prog, _ := Compile(reg, "size,disk,rate")
 
-	expected := "Alexa" // Truncated to 5
-	if result != expected {
-		t.Errorf("expected %q, got %q", expected, result)
+	file := testFile{Inode: 987654321, Size: 1536 << 20, Mode: 12345}
+	line := make([]byte, 0, 256)
+	tmp := make([]byte, 0, 64)
+
+	var buf bytes.Buffer
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		prog.WriteRow(&buf, &file, &tmp, &line)
 	}
 }
 
-// Benchmark the hot path - formatting rows
-func BenchmarkWriteRow(b *testing.B) {
-	reg := NewRegistry[testPerson]()
+func BenchmarkWriteRowGrouped(b *testing.B) {
+	reg := NewRegistry[testLedger]()
 
-	reg.Field("name", "Name", "Test").
-		Width(15).
-		String((*testPerson).GetName).
+	reg.Field("count", "Count", "Test").
+		Width(14).
+		Align(AlignRight).
+		Int(func(l *testLedger) int { return l.Count }).
 		Register()
 
-	reg.Field("age", "Age", "Test").
-		Width(5).
-		Int((*testPerson).GetAge).
+	reg.Field("amount", "Amount", "Test").
+		Width(16).
+		Align(AlignDecimal).
+		Float(2, func(l *testLedger) float64 { return l.Amount }).
+		Number(NumberDE).
 		Register()
 
-	reg.Field("temp", "Temp", "Test").
-		Width(8).
-		Float(2, (*testPerson).GetTemp).
+	prog, _ := CompileWithOptions(reg, "count:in,amount:acct", Options{Number: NumberEN})
+
+	row := testLedger{Count: 1234567890, Amount: -9876543.21}
+	line := make([]byte, 0, 256)
+	tmp := make([]byte, 0, 64)
+
+	var buf bytes.Buffer
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		prog.WriteRow(&buf, &row, &tmp, &line)
+	}
+}
+
+func BenchmarkWriteRowEngineering(b *testing.B) {
+	reg := NewRegistry[testLedger]()
+
+	reg.Field("amount", "Amount", "Test").
+		Width(12).
+		Align(AlignRight).
+		Float(2, func(l *testLedger) float64 { return l.Amount }).
+		Format("%eng").
 		Register()
 
-	prog, _ := Compile(reg, "name,age,temp")
+	reg.Field("sig", "Sig", "Test").
+		Width(10).
+		Float(4, func(l *testLedger) float64 { return l.Amount }).
+		Format("%sig").
+		Register()
 
-	person := testPerson{Name: "Alice", Age: 30, Temp: 98.6}
+	prog, _ := Compile(reg, "amount,sig")
+
+	row := testLedger{Amount: 0.000123456}
 	line := make([]byte, 0, 256)
 	tmp := make([]byte, 0, 64)
 
@@ -379,7 +3049,7 @@ func BenchmarkWriteRow(b *testing.B) {
 
 	for i := 0; i < b.N; i++ {
 		buf.Reset()
-		prog.WriteRow(&buf, &person, &tmp, &line)
+		prog.WriteRow(&buf, &row, &tmp, &line)
 	}
 }
 
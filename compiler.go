@@ -1,17 +1,60 @@
 package colprint
 
 import (
+	"errors"
 	"fmt"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Compile creates an optimized formatting program from a field specification.
 //
-// The spec is a comma-separated list of field names, with optional features:
+// The spec is a comma-separated list of field names, with optional features
+// (see parseFieldSpec for the full modifier grammar):
 //   - Field width override: "name:20" sets width to 20
-//   - Default expansion: "@default" expands to collection's default fields
-//   - Collection expansion: "@collection_name" expands to collection fields
+//   - Precision: "cpu:8.1" is 8 wide with one fraction digit; "cpu:.1"
+//     sets only the precision
+//   - Header rename: "rss=Memory" shows "Memory" as the header
+//   - Format hint: "start:%Y-%m-%d" or "cpu:%e"; integer fields take %d,
+//     %x, %X, %o and %b with an optional '#' prefix and zero padding, as
+//     in "addr:%#016x"; Float fields take %f, %e, %eng, %g, %sig and
+//     %si, Size fields a style and unit ("%si", "%iec@M"), Time fields
+//     strftime verbs (%Y %m %d %H %M %S %z %Z %b %a %p), a Go layout
+//     ("%15:04") or a layout name ("%date", "%relative"), Duration fields
+//     %compact, %seconds or %clock, and CustomFormat fields interpret it
+//     themselves
+//   - Time zone: "start:utc" or "start:local" for Time fields
+//   - Number locale: "count:en" groups digits as 1,234,567; also de, fr
+//     and in (lakh grouping). "acct" puts negatives in parentheses, as in
+//     "pnl:en:acct"
+//   - Alignment override: "name:>20" right-aligns; "<" is left, "^" center,
+//     "=" decimal point. The width may be omitted, as in "cpu:="
+//   - Truncation override: "path:30:lead" shortens over-wide values with a
+//     leading ellipsis. Keywords: cut, ellipsis, lead, middle, overflow, hash
+//   - Word wrapping: "desc:40:wrap" wraps long values onto extra lines
+//   - Default expansion: "@default" expands to the registry default (see
+//     Registry.SetDefault); it is an error if the default is ambiguous
+//   - View expansion: "@long" expands to a view level (see DefineView)
+//   - Collection expansion: "@collection_name" expands to the collection's
+//     default spec, or to all its members; "@proc.basic" names a
+//     collection of the proc sub-registry
+//   - All fields: "@all" expands to every field, sub-registries included
+//   - Glob patterns: "io_*" expands to the matching fields (also "?" and
+//     "[...]"), case-insensitively; modifiers apply to each match
+//   - Removal: "-name", "-@collection" or "-io_*" removes those fields from
+//     the columns listed so far
+//   - Inline collections: "@mine=pid,cmd,rss:12;" defines @mine and shows
+//     its fields; the definition runs to the next ';' or the end of the
+//     spec, and later tokens may use @mine (see Program.Layouts)
+//   - Qualified names: "proc.pid" selects pid from the sub-registry with
+//     namespace or name "proc" (see Registry.SetNamespace); an unqualified
+//     name found in several sub-registries is an error
+//
+// Tokens apply left to right. A field listed twice is shown once, where it
+// first appears and with that occurrence's modifiers.
 //
 // Examples:
 //
@@ -19,8 +62,17 @@ import (
 //	Compile(reg, "name:20,age:5,email:30")
 //	Compile(reg, "@default,extra_field")
 //	Compile(reg, "@basic,@perf")
+//	Compile(reg, "@default,-email,io_*")
+//
+// Returns an error if any field name is invalid, a collection doesn't exist
+// or a pattern matches nothing. Problems with the spec are reported
+// together as SpecErrors, one *SpecError per bad token with its offset and
+// close-match suggestions:
 //
-// Returns an error if any field name is invalid or a collection doesn't exist.
+//	var serr *colprint.SpecError
+//	if errors.As(err, &serr) {
+//	    fmt.Fprintln(os.Stderr, serr.Caret())
+//	}
 func Compile[T any](reg *Registry[T], spec string) (*Program[T], error) {
 	return CompileWithOptions(reg, spec, Options{
 		Separator: "  ", // Default: two spaces between columns
@@ -34,7 +86,7 @@ func CompileWithOptions[T any](reg *Registry[T], spec string, opts Options) (*Pr
 	}
 
 	// Parse spec into field list
-	fields, err := parseSpec(reg, spec)
+	fields, st, err := parseSpec(reg, spec, &opts)
 	if err != nil {
 		return nil, err
 	}
@@ -42,126 +94,783 @@ func CompileWithOptions[T any](reg *Registry[T], spec string, opts Options) (*Pr
 	if len(fields) == 0 {
 		return nil, fmt.Errorf("no fields specified")
 	}
+	for _, f := range fields {
+		if err := checkFormat(f); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &Program[T]{
+		fields:   fields,
+		spec:     canonicalSpec(reg, fields),
+		warnings: st.warnings,
+		layouts:  st.defs,
+		opts:     opts,
+		maxWidth: opts.MaxLineWidth,
+	}
+	if p.maxWidth <= 0 && opts.FitTerminal {
+		p.maxWidth = terminalWidth()
+	}
+	p.build()
+
+	return p, nil
+}
+
+// build creates the header, underline and column writers from p.fields.
+// It runs again whenever the fields change, as after Fit.
+func (p *Program[T]) build() {
+	fields, opts := p.fields, p.opts
 
 	// Set defaults - separator can be empty string (no spacing)
 	sep := opts.Separator
+	p.separator = []byte(sep)
+	p.head, p.foot, p.header, p.underline = nil, nil, nil, nil
+	p.newline = []byte{'\n'}
 
-	p := &Program[T]{
-		separator: []byte(sep),
+	// Fit the line width, shrinking and dropping columns as needed
+	p.dropped = nil
+	if p.maxWidth > 0 && opts.Format == FormatTable {
+		fields, p.dropped = layout(fields, textWidth(sep), p.maxWidth)
 	}
+	p.shown = fields
 
 	// Build header and underline
-	if !opts.NoHeader {
-		p.header = buildHeader(fields, sep, opts.NoPadding, opts.PadLastColumn)
-	}
-	if !opts.NoUnderline {
-		p.underline = buildUnderline(p.header)
+	switch opts.Format {
+	case FormatCSV, FormatTSV:
+		p.buildDelimited()
+	case FormatJSONLines, FormatJSON:
+		p.buildJSON()
+	case FormatMarkdown:
+		p.buildMarkdown()
+	case FormatHTML:
+		p.buildHTML()
+	default:
+		if !opts.NoHeader {
+			p.header = buildHeader(fields, sep, opts.NoPadding, opts.PadLastColumn)
+			p.head = append(append([]byte(nil), p.header...), '\n')
+		}
+		if !opts.NoUnderline {
+			p.underline = buildUnderline(p.header)
+		}
 	}
 
 	// Build optimized column writers
 	p.columns = make([]compiledCol[T], len(fields))
+	p.wrapCols = 0
+	for _, f := range fields {
+		if f.Wrap {
+			p.wrapCols++
+		}
+	}
 	lastIdx := len(fields) - 1
 	for i, f := range fields {
+		if opts.NoPadding {
+			f.Align = AlignLeft
+		}
 		isLast := i == lastIdx
 		noPad := opts.NoPadding || (isLast && !opts.PadLastColumn)
-		p.columns[i] = makeWriter(f, noPad)
+		p.columns[i] = makeWriter(f, noPad, &p.opts)
 	}
-
-	return p, nil
 }
 
 // parseSpec parses a field specification string.
-func parseSpec[T any](reg *Registry[T], spec string) ([]Field[T], error) {
-	tokens := strings.Split(spec, ",")
-	var fields []Field[T]
+//
+// Tokens are applied left to right: fields, globs and references append to
+// the list, and "-" tokens remove every matching field from the list so
+// far. Duplicates are then dropped, keeping each field's first occurrence
+// along with its overrides.
+//
+// Deprecated fields are replaced by their replacement, if they have one,
+// and reported as warnings.
+//
+// opts, which may be nil, supplies the number format that an "acct"
+// modifier builds on.
+func parseSpec[T any](reg *Registry[T], spec string, opts *Options) ([]Field[T], *parseState, error) {
+	st := &parseState{}
+	if opts != nil {
+		st.number = opts.Number
+	}
+	fields, err := parseSpecRefs(reg, spec, st)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dedupeFields(fields), st, nil
+}
 
-	for _, tok := range tokens {
-		tok = strings.TrimSpace(tok)
-		if tok == "" {
-			continue
+// parseState is carried through the expansion of a spec.
+type parseState struct {
+	expanding []string // references being expanded, outermost first
+	warnings  []SpecWarning
+	defs      []Layout     // inline definitions, with canonical specs
+	scope     int          // index in defs of the spec being expanded
+	number    NumberFormat // Options.Number
+}
+
+// refNames returns names followed by "@name" for each inline definition.
+func (st *parseState) refNames(names []string) []string {
+	for _, l := range st.defs {
+		names = append(names, "@"+l.Name)
+	}
+	return names
+}
+
+// def returns the inline definition called name, innermost first, and
+// whether it belongs to the spec being expanded.
+func (st *parseState) def(name string) (l Layout, local, ok bool) {
+	for i := len(st.defs) - 1; i >= 0; i-- {
+		if st.defs[i].Name == name {
+			return st.defs[i], i >= st.scope, true
 		}
+	}
+	return Layout{}, false, false
+}
+
+// parseSpecRefs parses a spec found by expanding the views and collections
+// in st.expanding, which is used to reject a reference to itself. Every bad
+// token is reported, as SpecErrors.
+func parseSpecRefs[T any](reg *Registry[T], spec string, st *parseState) ([]Field[T], error) {
+	ast, err := ParseSpec(spec)
+	var errs SpecErrors
+	if err != nil {
+		errs = append(errs, err.(SpecErrors)...)
+	}
+
+	fields := resolveItems(reg, ast.Items, spec, st, &errs)
+	if len(errs) > 0 {
+		// Syntax errors were found first; report in spec order
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Pos < errs[j].Pos })
+		return nil, errs
+	}
+	return fields, nil
+}
 
-		// Check for @ prefix (collection or @default)
-		if strings.HasPrefix(tok, "@") {
-			name := tok[1:]
-
-			// Handle @default
-			if name == "default" {
-				// Find the default spec
-				// For now, use first defined default
-				for _, defSpec := range reg.defaults {
-					expanded, err := parseSpec(reg, defSpec)
-					if err != nil {
-						return nil, fmt.Errorf("expanding @default: %w", err)
-					}
-					fields = append(fields, expanded...)
-					break
-				}
+// resolveItems returns the fields a list of spec items selects, appending
+// any errors to errs. An inline definition selects its fields and records
+// them in st for later references.
+func resolveItems[T any](reg *Registry[T], items []SpecItem, spec string, st *parseState, errs *SpecErrors) []Field[T] {
+	var fields []Field[T]
+	for i := range items {
+		item := &items[i]
+		if item.Kind == ItemDefine {
+			n := len(*errs)
+			body := dedupeFields(resolveItems(reg, item.Items, spec, st, errs))
+			if len(*errs) > n {
 				continue
 			}
-
-			// Handle @collection
-			if defSpec, ok := reg.defaults[name]; ok {
-				expanded, err := parseSpec(reg, defSpec)
-				if err != nil {
-					return nil, fmt.Errorf("expanding @%s: %w", name, err)
-				}
-				fields = append(fields, expanded...)
+			def := Layout{Name: item.Name, Spec: canonicalSpec(reg, body)}
+			if _, local, _ := st.def(item.Name); local {
+				*errs = append(*errs, &SpecError{Spec: spec, Pos: item.NamePos, Token: item.Name,
+					Msg: fmt.Sprintf("%q is defined more than once", "@"+item.Name)})
 				continue
 			}
+			st.defs = append(st.defs, def)
+			fields = append(fields, body...)
+			continue
+		}
 
-			return nil, fmt.Errorf("unknown collection: @%s", name)
+		matched, se := resolveItem(reg, item, spec, st)
+		if se != nil {
+			se.Spec = spec
+			*errs = append(*errs, se)
+			continue
+		}
+		if item.Remove {
+			fields = removeFields(fields, matched)
+		} else {
+			fields = append(fields, matched...)
 		}
+	}
+	return fields
+}
 
-		// Parse field name and optional width override
-		fieldName, width, hasWidth, err := parseFieldSpec(tok)
+// resolveItem returns the fields a spec item stands for, with any
+// modifiers applied.
+func resolveItem[T any](reg *Registry[T], item *SpecItem, spec string, st *parseState) ([]Field[T], *SpecError) {
+	itemError := func(err error) *SpecError {
+		return &SpecError{Pos: item.Pos, Token: item.String(), Msg: err.Error()}
+	}
+
+	var fields []Field[T]
+	switch item.Kind {
+	case ItemRef:
+		expanded, err := expandRef(reg, item.Name, st)
+		if err == errUnknownRef {
+			return nil, &SpecError{
+				Pos:         item.NamePos - 1,
+				Token:       "@" + item.Name,
+				Msg:         fmt.Sprintf("unknown collection %q", "@"+item.Name),
+				Suggestions: suggest(item.Name, st.refNames(reg.refNames())),
+			}
+		}
 		if err != nil {
-			return nil, err
+			// Keep the inner error, and its suggestions, as data
+			se := &SpecError{Pos: item.Pos, Token: item.String(), Msg: refMessage(err), Err: err}
+			var inner *SpecError
+			if errors.As(err, &inner) {
+				se.Suggestions = inner.Suggestions
+			}
+			return nil, se
 		}
+		return expanded, nil
 
-		// Look up field
-		field, ok := reg.get(fieldName)
+	case ItemPattern:
+		matched, err := matchFields(reg, item.Name)
+		if err != nil {
+			return nil, &SpecError{Pos: item.NamePos, Token: item.Name, Msg: err.Error()}
+		}
+		fields = matched
+
+	default:
+		field, candidates, ok := reg.lookup(item.Name)
+		if !ok && len(candidates) > 0 {
+			return nil, &SpecError{
+				Pos:         item.NamePos,
+				Token:       item.Name,
+				Msg:         fmt.Sprintf("field %q is ambiguous", item.Name),
+				Suggestions: candidates,
+			}
+		}
 		if !ok {
-			return nil, fmt.Errorf("unknown field: %q", fieldName)
+			return nil, &SpecError{
+				Pos:         item.NamePos,
+				Token:       item.Name,
+				Msg:         fmt.Sprintf("unknown field %q", item.Name),
+				Suggestions: suggest(item.Name, reg.fieldNames()),
+			}
+		}
+		if field.Deprecated {
+			field = migrate(reg, field, item, spec, st)
 		}
+		fields = []Field[T]{field}
+	}
 
-		// Apply width override
-		if hasWidth {
-			if width <= 0 {
-				return nil, fmt.Errorf("invalid width %d for field %q", width, fieldName)
+	for i := range fields {
+		if err := applyColSpec(item.cs, &fields[i], st.number); err != nil {
+			if m, ok := rejectedModifier(item, fields[i], st.number); ok {
+				return nil, &SpecError{Pos: m.Pos, Token: m.Text, Msg: err.Error()}
 			}
-			field.Width = width
+			return nil, itemError(err)
+		}
+		if !item.cs.hasFormat {
+			continue
+		}
+		if err := checkFormat(fields[i]); err != nil {
+			m := item.Modifiers[len(item.Modifiers)-1] // the format comes last
+			return nil, &SpecError{Pos: m.Pos, Token: m.Text, Msg: err.Error()}
+		}
+	}
+	return fields, nil
+}
+
+// rejectedModifier returns the modifier of item that applyColSpec rejects
+// for field on its own, such as the "0" of "name:0", so an error can point
+// at it rather than at the whole token.
+func rejectedModifier[T any](item *SpecItem, field Field[T], number NumberFormat) (SpecModifier, bool) {
+	for _, m := range item.Modifiers {
+		cs, _, err := parseToken(item.cs.name + ":" + m.Text)
+		f := field
+		if err == nil && applyColSpec(cs, &f, number) != nil {
+			return m, true
 		}
+	}
+	return SpecModifier{}, false
+}
+
+// migrate returns the field to use in place of a deprecated one: its
+// replacement, if it has one that exists, or else the field itself. Either
+// way a warning is recorded.
+func migrate[T any](reg *Registry[T], field Field[T], item *SpecItem, spec string, st *parseState) Field[T] {
+	w := SpecWarning{
+		Spec:        spec,
+		Pos:         item.NamePos,
+		Token:       item.Name,
+		Replacement: field.Replacement,
+	}
+	if field.Replacement != "" {
+		if repl, ok := reg.get(field.Replacement); ok && !repl.Deprecated {
+			w.Msg = fmt.Sprintf("field %q is deprecated; using %q instead", item.Name, repl.Name)
+			st.warnings = append(st.warnings, w)
+			return repl
+		}
+	}
+	w.Replacement = ""
+	w.Msg = fmt.Sprintf("field %q is deprecated", item.Name)
+	st.warnings = append(st.warnings, w)
+	return field
+}
+
+// isGlob reports whether a field name is a glob pattern.
+func isGlob(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
 
-		fields = append(fields, field)
+// matchFields returns the fields of reg and its sub-registries whose names
+// match the glob pattern, case-insensitively, in registry order. A pattern
+// with a dot is matched against qualified names too, so "proc.*" selects
+// every field of the proc sub-registry. It is an error for nothing to
+// match.
+func matchFields[T any](reg *Registry[T], pattern string) ([]Field[T], error) {
+	pattern = strings.ToLower(pattern)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
 	}
 
+	entries := reg.entries(nil, true)
+	shared := sharedNames(entries)
+	qualified := strings.Contains(pattern, ".")
+
+	var fields []Field[T]
+	for _, e := range entries {
+		if e.field.Deprecated {
+			continue
+		}
+		ok, _ := path.Match(pattern, strings.ToLower(e.field.Name))
+		if !ok && qualified && e.qual != "" {
+			ok, _ = path.Match(pattern, strings.ToLower(e.qual+"."+e.field.Name))
+		}
+		if ok {
+			fields = append(fields, e.qualified(shared))
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields match %q", pattern)
+	}
 	return fields, nil
 }
 
-// parseFieldSpec parses a single field token (name or name:width).
-func parseFieldSpec(tok string) (name string, width int, hasWidth bool, err error) {
-	idx := strings.IndexByte(tok, ':')
-	if idx < 0 {
-		return strings.TrimSpace(tok), 0, false, nil
+// removeFields returns fields without any field named in drop.
+func removeFields[T any](fields, drop []Field[T]) []Field[T] {
+	kept := fields[:0]
+	for _, f := range fields {
+		if !containsField(drop, f.Name) {
+			kept = append(kept, f)
+		}
 	}
+	return kept
+}
 
-	name = strings.TrimSpace(tok[:idx])
-	widthStr := strings.TrimSpace(tok[idx+1:])
+// dedupeFields drops every field whose name already appeared earlier.
+func dedupeFields[T any](fields []Field[T]) []Field[T] {
+	kept := fields[:0]
+	for _, f := range fields {
+		if !containsField(kept, f.Name) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
 
-	if widthStr == "" {
-		return "", 0, false, fmt.Errorf("empty width in %q", tok)
+// containsField reports whether fields includes one named name.
+func containsField[T any](fields []Field[T], name string) bool {
+	for i := range fields {
+		if fields[i].Name == name {
+			return true
+		}
 	}
+	return false
+}
 
-	width, err = strconv.Atoi(widthStr)
-	if err != nil {
-		return "", 0, false, fmt.Errorf("invalid width %q in %q", widthStr, tok)
+// expandRef returns the fields of @name: a view, a collection's default
+// spec, @default, or @all. Views and collections named "all" take
+// precedence over the built-in @all.
+func expandRef[T any](reg *Registry[T], name string, st *parseState) ([]Field[T], error) {
+	for _, outer := range st.expanding {
+		if outer == name {
+			return nil, fmt.Errorf("@%s refers to itself", name)
+		}
+	}
+	// Inline definitions in the expanded spec are local to it, so a view
+	// that defines one can be expanded more than once
+	n, scope := len(st.defs), st.scope
+	st.expanding = append(st.expanding, name)
+	st.scope = n
+	defer func() {
+		st.expanding = st.expanding[:len(st.expanding)-1]
+		st.defs, st.scope = st.defs[:n], scope
+	}()
+
+	// Inline definitions take precedence over the registry
+	if def, _, ok := st.def(name); ok {
+		return parseSpecRefs(reg, def.Spec, st)
+	}
+
+	if v, ok := reg.views[name]; ok {
+		var fields []Field[T]
+		if v.base != "" {
+			base, err := expandRef(reg, v.base, st)
+			if err != nil {
+				return nil, &refError{Name: name, Err: err}
+			}
+			fields = base
+		}
+		own, err := parseSpecRefs(reg, v.spec, st)
+		if err != nil {
+			return nil, &refError{Name: name, Err: err}
+		}
+		return append(fields, own...), nil
+	}
+
+	if name == "default" {
+		defSpec, _, err := reg.defaultSpec()
+		if err != nil {
+			return nil, err
+		}
+		fields, err := parseSpecRefs(reg, defSpec, st)
+		if err != nil {
+			return nil, &refError{Name: "default", Err: err}
+		}
+		return fields, nil
+	}
+
+	if owner, local, ok := reg.findCollection(name); ok {
+		return expandCollection(reg, owner, name, local, st)
+	}
+
+	if name == "all" {
+		return reg.allFields(), nil
+	}
+
+	return nil, errUnknownRef
+}
+
+// expandCollection returns the fields of the collection called name in
+// owner, reg or one of its sub-registries: the fields of its default spec,
+// or else all its members. The members are checked either way. ref is the
+// collection as the spec names it, for errors from its default spec.
+func expandCollection[T any](reg, owner *Registry[T], ref, name string, st *parseState) ([]Field[T], error) {
+	var members []Field[T]
+	if _, ok := owner.collections[name]; ok {
+		names, err := owner.memberNames(name, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range names {
+			f, ok := owner.get(n)
+			if !ok {
+				return nil, fmt.Errorf("collection @%s lists unknown field %q", name, n)
+			}
+			members = append(members, f)
+		}
+	}
+
+	fields := members
+	if spec := owner.defaults[name]; spec != "" {
+		var err error
+		if fields, err = parseSpecRefs(owner, spec, st); err != nil {
+			return nil, &refError{Name: ref, Err: err}
+		}
+	}
+	if owner == reg {
+		return fields, nil
+	}
+
+	// Name shared fields as reg knows them
+	qual := owner.qualifier()
+	shared := sharedNames(reg.entries(nil, true))
+	for i, f := range fields {
+		if qual != "" && !strings.Contains(f.Name, ".") && shared[strings.ToLower(f.Name)] > 1 {
+			fields[i].Name = qual + "." + f.Name
+		}
+	}
+	return fields, nil
+}
+
+// applyColSpec sets the overrides of cs on field. An explicit width also
+// pins the column against automatic sizing. An "acct" modifier on a field
+// without a number format of its own builds on number.
+func applyColSpec[T any](cs colSpec, field *Field[T], number NumberFormat) error {
+	if cs.hasWidth {
+		if cs.width <= 0 {
+			return fmt.Errorf("invalid width %d for field %q", cs.width, cs.name)
+		}
+		field.Width = cs.width
+		field.MinWidth, field.MaxWidth = cs.width, cs.width
+	}
+	if cs.hasAlign {
+		field.Align = cs.align
+	}
+	if cs.hasTrunc {
+		field.Truncate = cs.trunc
+	}
+	if cs.wrap {
+		field.Wrap = true
+	}
+	if cs.hasDisplay {
+		field.Display = cs.display
+	}
+	if cs.hasPrec {
+		if field.Kind == KindString {
+			return fmt.Errorf("field %q is a string and takes no precision", field.Name)
+		}
+		field.Precision = cs.prec
+	}
+	if cs.hasFormat {
+		field.Format = cs.format
+	}
+	if cs.loc != nil {
+		if field.Kind != KindTime {
+			return fmt.Errorf("field %q is not a time and takes no time zone", field.Name)
+		}
+		field.Location = cs.loc
+	}
+	if cs.number != nil || cs.acct {
+		if !takesNumberFormat(field.Kind) {
+			return fmt.Errorf("field %q is not a number and takes no number format", field.Name)
+		}
+		nf := number
+		if cs.number != nil {
+			nf = *cs.number
+		} else if field.Number != nil {
+			nf = *field.Number
+		}
+		if cs.acct {
+			nf.Accounting = true
+		}
+		field.Number = &nf
+	}
+	return nil
+}
+
+// checkFormat reports whether a field understands its format hint.
+func checkFormat[T any](f Field[T]) error {
+	if f.Format == "" {
+		return nil
+	}
+	switch f.Kind {
+	case KindInt, KindInt64, KindUint64:
+		ifmt, ok := parseIntFormat(f.Format)
+		if !ok {
+			return fmt.Errorf("invalid format %q for field %q: want %%d, %%x, %%X, %%o or %%b, as in %%#08x", f.Format, f.Name)
+		}
+		if ifmt.zeroPadded() && f.Number != nil && f.Number.Group != "" {
+			return fmt.Errorf("format %q of field %q pads with zeros, which are not grouped; drop the digit grouping", f.Format, f.Name)
+		}
+		return nil
+	case KindFloat:
+		if _, ok := floatStyles[f.Format]; !ok {
+			return fmt.Errorf("invalid format %q for field %q: want %%f, %%e, %%eng, %%g, %%sig or %%si", f.Format, f.Name)
+		}
+		return nil
+	case KindSize:
+		if _, ok := parseSizeFormat(f.Format); !ok {
+			return fmt.Errorf("invalid format %q for field %q: want %%iec, %%binary, %%si, %%iec-long or %%si-long, optionally with a unit as in %%iec@M", f.Format, f.Name)
+		}
+		return nil
+	case KindCustom:
+		if f.GetCustomFormat != nil {
+			return nil
+		}
+	case KindTime:
+		if _, ok := strftimeLayout(f.Format); isStrftime(f.Format) && !ok {
+			return fmt.Errorf("invalid format %q for field %q: want the strftime verbs %%Y %%m %%d %%H %%M %%S %%z %%Z %%b %%a %%p, a Go layout such as %%2006-01-02, or %s", f.Format, f.Name, strings.Join(timeLayoutNames(), ", "))
+		}
+		return nil
+	case KindDuration:
+		if _, ok := durationStyle(f.Format); !ok {
+			return fmt.Errorf("invalid format %q for field %q: want %%compact, %%seconds or %%clock", f.Format, f.Name)
+		}
+		return nil
+	}
+	return fmt.Errorf("field %q does not take a format", f.Name)
+}
+
+// colSpec holds the overrides parsed from a single field token.
+type colSpec struct {
+	name       string
+	display    string
+	hasDisplay bool
+	width      int
+	hasWidth   bool
+	prec       int
+	hasPrec    bool
+	align      Align
+	hasAlign   bool
+	trunc      Truncate
+	hasTrunc   bool
+	wrap       bool
+	format     string
+	hasFormat  bool
+	loc        *time.Location
+	number     *NumberFormat
+	acct       bool
+}
+
+// alignPrefixes maps the alignment characters accepted before a width.
+var alignPrefixes = map[byte]Align{
+	'<': AlignLeft,
+	'>': AlignRight,
+	'^': AlignCenter,
+	'=': AlignDecimal,
+}
+
+// truncateNames maps the truncation keywords accepted in a field token.
+var truncateNames = map[string]Truncate{
+	"cut":      TruncateCut,
+	"ellipsis": TruncateEllipsis,
+	"lead":     TruncateLeading,
+	"middle":   TruncateMiddle,
+	"overflow": TruncateOverflow,
+	"hash":     TruncateHash,
+}
+
+// keywordModifiers maps the word modifiers accepted in a field token to
+// the override they set. New keywords are added here.
+var keywordModifiers = map[string]func(cs *colSpec){
+	"wrap":  func(cs *colSpec) { cs.wrap = true },
+	"utc":   func(cs *colSpec) { cs.loc = time.UTC },
+	"local": func(cs *colSpec) { cs.loc = time.Local },
+	"acct":  func(cs *colSpec) { cs.acct = true },
+}
+
+func init() {
+	for name, trunc := range truncateNames {
+		keywordModifiers[name] = func(cs *colSpec) {
+			cs.trunc, cs.hasTrunc = trunc, true
+		}
+	}
+	for name, nf := range numberLocales {
+		keywordModifiers[name] = func(cs *colSpec) { cs.number = &nf }
+	}
+}
+
+// parseFieldSpec parses a single field token.
+//
+// The grammar is
+//
+//	token    = name ["=" header] {":" modifier}
+//	modifier = [align] [width] ["." precision]   e.g. >20, 8.1, =, .3
+//	         | keyword                            wrap, cut, utc, de, acct, ...
+//	         | "%" format                         e.g. %Y-%m-%d, %e
+//	align    = "<" | ">" | "^" | "="
+//
+// The header runs up to the first ':'. A format runs to the end of the
+// token, so it may itself contain colons; it must be the last modifier.
+// Keywords are matched case-insensitively.
+func parseFieldSpec(tok string) (colSpec, error) {
+	cs, _, err := parseToken(tok)
+	return cs, err
+}
+
+// parseToken parses a field token as parseFieldSpec does, also returning
+// its modifiers with their offsets in tok.
+//
+// Errors are *SpecError values with Pos relative to tok.
+func parseToken(tok string) (colSpec, []SpecModifier, error) {
+	rest, restPos := "", len(tok)
+	name := tok
+	if i := strings.IndexByte(tok, ':'); i >= 0 {
+		name, rest, restPos = tok[:i], tok[i+1:], i+1
+	}
+
+	var cs colSpec
+	if i := strings.IndexByte(name, '='); i >= 0 {
+		cs.display = strings.TrimSpace(name[i+1:])
+		if cs.display == "" {
+			return colSpec{}, nil, tokenError(i, tok[i:len(name)], "empty header in %q", tok)
+		}
+		cs.hasDisplay = true
+		name = name[:i]
+	}
+	cs.name = strings.TrimSpace(name)
+	if cs.name == "" {
+		return colSpec{}, nil, tokenError(0, tok, "missing field name in %q", tok)
+	}
+
+	var mods []SpecModifier
+	more := strings.IndexByte(tok, ':') >= 0
+	for more {
+		mod, modPos := rest, restPos
+		if strings.HasPrefix(strings.TrimSpace(rest), "%") {
+			more = false // the format takes the rest of the token
+		} else if next := strings.IndexByte(rest, ':'); next >= 0 {
+			mod, rest, restPos = rest[:next], rest[next+1:], restPos+next+1
+		} else {
+			more = false
+		}
+		modPos, mod = trimSpan(mod, modPos)
+		mods = append(mods, SpecModifier{Text: mod, Pos: modPos})
+
+		switch {
+		case mod == "":
+			return colSpec{}, nil, tokenError(modPos-1, ":", "empty modifier in %q", tok)
+		case mod[0] == '%':
+			if len(mod) == 1 {
+				return colSpec{}, nil, tokenError(modPos, mod, "empty format in %q", tok)
+			}
+			cs.format, cs.hasFormat = mod, true
+		default:
+			if set, ok := keywordModifiers[strings.ToLower(mod)]; ok {
+				set(&cs)
+				continue
+			}
+			if err := parseLayoutModifier(&cs, mod, modPos, tok); err != nil {
+				return colSpec{}, nil, err
+			}
+		}
+	}
+
+	return cs, mods, nil
+}
+
+// parseLayoutModifier parses an [align][width][.precision] modifier found
+// at offset pos of tok.
+func parseLayoutModifier(cs *colSpec, mod string, pos int, tok string) *SpecError {
+	whole := mod
+	if align, ok := alignPrefixes[mod[0]]; ok {
+		cs.align, cs.hasAlign = align, true
+		mod = mod[1:]
+		pos++
+	}
+
+	widthStr, precStr, hasPrec := strings.Cut(mod, ".")
+	if widthStr != "" {
+		width, err := strconv.Atoi(widthStr)
+		if err != nil {
+			if !cs.hasAlign && !hasPrec && !isDigit(widthStr[0]) {
+				e := tokenError(pos, whole, "unknown modifier %q in %q", widthStr, tok)
+				e.Suggestions = suggest(widthStr, modifierKeywords())
+				return e
+			}
+			return tokenError(pos, widthStr, "invalid width %q in %q", widthStr, tok)
+		}
+		cs.width, cs.hasWidth = width, true
+	}
+	if hasPrec {
+		prec, err := strconv.Atoi(precStr)
+		if err != nil || prec < 0 {
+			return tokenError(pos+len(widthStr)+1, precStr, "invalid precision %q in %q", precStr, tok)
+		}
+		cs.prec, cs.hasPrec = prec, true
 	}
+	return nil
+}
+
+// modifierKeywords returns the keyword modifiers, sorted.
+func modifierKeywords() []string {
+	names := make([]string, 0, len(keywordModifiers))
+	for name := range keywordModifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tokenError returns a *SpecError for token at offset pos. The caller
+// fills in Spec and shifts Pos once the token's place in it is known.
+func tokenError(pos int, token, format string, args ...any) *SpecError {
+	return &SpecError{Pos: pos, Token: token, Msg: fmt.Sprintf(format, args...)}
+}
 
-	return name, width, true, nil
+// isDigit reports whether b is an ASCII digit.
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
 }
 
 // buildHeader constructs the header line.
+//
+// Each header is aligned and truncated like its column; decimal-aligned
+// columns get a right-aligned header, and headers are never overflowed or
+// replaced by '#' marks.
 func buildHeader[T any](fields []Field[T], sep string, noPadding, padLast bool) []byte {
 	var buf []byte
 	lastIdx := len(fields) - 1
@@ -170,97 +879,229 @@ func buildHeader[T any](fields []Field[T], sep string, noPadding, padLast bool)
 			buf = append(buf, sep...)
 		}
 		isLast := i == lastIdx
-		if noPadding || (isLast && !padLast) {
-			// No padding for this column
-			display := f.Display
-			if len(display) > f.Width {
-				display = display[:f.Width]
-			}
-			buf = append(buf, display...)
-		} else {
-			// Pad column to width
-			buf = padLeft(buf, f.Display, f.Width)
+		c := cell{
+			width: f.Width,
+			align: f.Align,
+			trunc: f.Truncate,
+			trim:  noPadding || (isLast && !padLast),
+		}
+		if noPadding {
+			c.align = AlignLeft
+		} else if c.align == AlignDecimal {
+			c.align = AlignRight
 		}
+		// Markers meant for values would hide the header
+		if c.trunc == TruncateHash || c.trunc == TruncateOverflow {
+			c.trunc = TruncateCut
+		}
+		buf = padCell(buf, f.Display, &c)
 	}
 	return buf
 }
 
 // buildUnderline creates an underline matching the header.
+//
+// Every character of header text is underlined with one dash per display
+// cell, so wide characters get two.
 func buildUnderline(header []byte) []byte {
-	underline := make([]byte, len(header))
-	for i, ch := range header {
-		if ch == ' ' {
-			underline[i] = ' '
+	underline := make([]byte, 0, len(header))
+	for i := 0; i < len(header); {
+		end, w := nextCluster(header, i)
+		if header[i] == ' ' {
+			underline = append(underline, ' ')
 		} else {
-			underline[i] = '-'
+			for j := 0; j < w; j++ {
+				underline = append(underline, '-')
+			}
 		}
+		i = end
 	}
 	return underline
 }
 
 // makeWriter creates an optimized writer closure for a field.
-func makeWriter[T any](f Field[T], noPad bool) compiledCol[T] {
+//
+// When noPad is set the column gets no trailing padding; leading padding
+// for right, center and decimal alignment is still written.
+//
+// Writers format into the free space at the end of tmp and restore its
+// length afterwards, so earlier contents of tmp (such as the pending text
+// of wrapped columns) survive.
+func makeWriter[T any](f Field[T], noPad bool, opts *Options) compiledCol[T] {
+	if fn := f.GetCustomFormat; fn != nil {
+		format := f.Format
+		f.GetCustom = func(dst []byte, v *T) []byte {
+			return fn(dst, v, format)
+		}
+	}
+	width, align := f.Width, f.Align
+	nf, grouped := fieldNumberFormat(f, opts)
+	c := &cell{
+		width: width,
+		align: align,
+		frac:  decimalFrac(f),
+		trunc: f.Truncate,
+		trim:  noPad,
+	}
+	if grouped {
+		c.setNumber(&nf)
+	}
+	col := compiledCol[T]{
+		width: width,
+		align: align,
+		cell:  *c,
+		wrap:  f.Wrap,
+	}
+
 	switch f.Kind {
 	case KindString:
-		// String field - left-aligned
-		// Always pad all columns (removed isLast check)
-		return compiledCol[T]{
-			width: f.Width,
-			write: func(line *[]byte, v *T, _ *[]byte) {
-				s := f.GetString(v)
-				*line = padLeft(*line, s, f.Width)
-			},
+		col.value = func(dst []byte, v *T) []byte {
+			return append(dst, f.GetString(v)...)
+		}
+		col.write = func(line *[]byte, v *T, _ *[]byte) {
+			s := f.GetString(v)
+			*line = padCell(*line, s, c)
 		}
 
 	case KindInt:
-		// Int field - left-aligned (Phase 2 will add right-align)
-		// Always pad all columns
-		return compiledCol[T]{
-			width: f.Width,
-			write: func(line *[]byte, v *T, tmp *[]byte) {
-				*tmp = (*tmp)[:0]
-				*tmp = strconv.AppendInt(*tmp, int64(f.GetInt(v)), 10)
-				*line = padBytesLeft(*line, *tmp, f.Width)
-			},
+		ifmt, _ := parseIntFormat(f.Format)
+		col.value = func(dst []byte, v *T) []byte {
+			return ifmt.appendInt(dst, int64(f.GetInt(v)))
+		}
+		col.write = func(line *[]byte, v *T, tmp *[]byte) {
+			start := len(*tmp)
+			*tmp = ifmt.appendInt(*tmp, int64(f.GetInt(v)))
+			*line = padCell(*line, (*tmp)[start:], c)
+			*tmp = (*tmp)[:start]
+		}
+
+	case KindInt64:
+		ifmt, _ := parseIntFormat(f.Format)
+		col.value = func(dst []byte, v *T) []byte {
+			return ifmt.appendInt(dst, f.GetInt64(v))
+		}
+		col.write = func(line *[]byte, v *T, tmp *[]byte) {
+			start := len(*tmp)
+			*tmp = ifmt.appendInt(*tmp, f.GetInt64(v))
+			*line = padCell(*line, (*tmp)[start:], c)
+			*tmp = (*tmp)[:start]
+		}
+
+	case KindUint64:
+		ifmt, _ := parseIntFormat(f.Format)
+		col.value = func(dst []byte, v *T) []byte {
+			return ifmt.appendUint(dst, f.GetUint64(v))
+		}
+		col.write = func(line *[]byte, v *T, tmp *[]byte) {
+			start := len(*tmp)
+			*tmp = ifmt.appendUint(*tmp, f.GetUint64(v))
+			*line = padCell(*line, (*tmp)[start:], c)
+			*tmp = (*tmp)[:start]
 		}
 
 	case KindFloat:
-		// Float field - left-aligned (Phase 2 will add right-align)
-		// Always pad all columns
-		prec := f.Precision
-		if prec < 0 {
-			prec = 2
+		ffmt := newFloatFormat(f, opts)
+		col.value = func(dst []byte, v *T) []byte {
+			return ffmt.append(dst, f.GetFloat(v))
 		}
-		return compiledCol[T]{
-			width: f.Width,
-			write: func(line *[]byte, v *T, tmp *[]byte) {
-				*tmp = (*tmp)[:0]
-				*tmp = strconv.AppendFloat(*tmp, f.GetFloat(v), 'f', prec, 64)
-				*line = padBytesLeft(*line, *tmp, f.Width)
-			},
+		col.write = func(line *[]byte, v *T, tmp *[]byte) {
+			start := len(*tmp)
+			*tmp = ffmt.append(*tmp, f.GetFloat(v))
+			*line = padCell(*line, (*tmp)[start:], c)
+			*tmp = (*tmp)[:start]
 		}
 
 	case KindCustom:
-		// Custom formatter - left-aligned
-		// Always pad all columns
-		return compiledCol[T]{
-			width: f.Width,
-			write: func(line *[]byte, v *T, tmp *[]byte) {
-				*tmp = (*tmp)[:0]
-				*tmp = f.GetCustom(*tmp, v)
-				*line = padBytesLeft(*line, *tmp, f.Width)
-			},
+		col.value = func(dst []byte, v *T) []byte {
+			return f.GetCustom(dst, v)
+		}
+		col.write = func(line *[]byte, v *T, tmp *[]byte) {
+			start := len(*tmp)
+			*tmp = f.GetCustom(*tmp, v)
+			*line = padCell(*line, (*tmp)[start:], c)
+			*tmp = (*tmp)[:start]
+		}
+
+	case KindSize:
+		sfmt, _ := parseSizeFormat(f.Format)
+		digits := f.Precision
+		col.value = func(dst []byte, v *T) []byte {
+			return sfmt.appendSize(dst, f.GetUint64(v), digits)
+		}
+		col.write = func(line *[]byte, v *T, tmp *[]byte) {
+			start := len(*tmp)
+			*tmp = sfmt.appendSize(*tmp, f.GetUint64(v), digits)
+			*line = padCell(*line, (*tmp)[start:], c)
+			*tmp = (*tmp)[:start]
+		}
+
+	case KindTime:
+		appendTime := timeAppender(f, opts.Now)
+		col.value = func(dst []byte, v *T) []byte {
+			return appendTime(dst, f.GetTime(v))
+		}
+		col.write = func(line *[]byte, v *T, tmp *[]byte) {
+			start := len(*tmp)
+			*tmp = appendTime(*tmp, f.GetTime(v))
+			*line = padCell(*line, (*tmp)[start:], c)
+			*tmp = (*tmp)[:start]
+		}
+
+	case KindDuration:
+		style, _ := durationStyle(f.Format)
+		prec := f.Precision
+		col.value = func(dst []byte, v *T) []byte {
+			return appendDuration(dst, f.GetDuration(v), style, prec)
+		}
+		col.write = func(line *[]byte, v *T, tmp *[]byte) {
+			start := len(*tmp)
+			*tmp = appendDuration(*tmp, f.GetDuration(v), style, prec)
+			*line = padCell(*line, (*tmp)[start:], c)
+			*tmp = (*tmp)[:start]
 		}
 
 	default:
 		// Unknown kind - emit spaces
-		return compiledCol[T]{
-			width: f.Width,
-			write: func(line *[]byte, _ *T, _ *[]byte) {
-				for i := 0; i < f.Width; i++ {
-					*line = append(*line, ' ')
-				}
-			},
+		col.value = func(dst []byte, _ *T) []byte {
+			return dst
+		}
+		col.write = func(line *[]byte, _ *T, _ *[]byte) {
+			*line = appendSpaces(*line, width)
+		}
+	}
+	if grouped {
+		value := col.value
+		col.value = func(dst []byte, v *T) []byte {
+			return nf.apply(value(dst, v), len(dst))
+		}
+		col.write = func(line *[]byte, v *T, tmp *[]byte) {
+			start := len(*tmp)
+			*tmp = nf.apply(value(*tmp, v), start)
+			*line = padCell(*line, (*tmp)[start:], c)
+			*tmp = (*tmp)[:start]
+		}
+	}
+	col.json = makeJSON(f)
+
+	return col
+}
+
+// decimalFrac returns the number of characters AlignDecimal reserves right
+// of the integer part: the '.' plus the fraction digits, and the exponent
+// of floats in 'e' notation.
+func decimalFrac[T any](f Field[T]) int {
+	prec := f.Precision
+	if f.Kind == KindFloat && prec < 0 {
+		prec = 2
+	}
+	if prec <= 0 {
+		return 0
+	}
+	if f.Kind == KindFloat {
+		switch floatStyles[f.Format] {
+		case floatExp, floatEng:
+			return prec + 1 + len("e+00")
 		}
 	}
+	return prec + 1
 }
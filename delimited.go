@@ -0,0 +1,102 @@
+package colprint
+
+import "unicode/utf8"
+
+// utf8BOM is the UTF-8 encoding of the byte order mark.
+const utf8BOM = "\uFEFF"
+
+// csvConfig fills in the defaults of o for format.
+func csvConfig(format Format, o CSVOptions) CSVOptions {
+	if o.Delimiter == 0 {
+		o.Delimiter = ','
+		if format == FormatTSV {
+			o.Delimiter = '\t'
+		}
+	}
+	if o.Quote == 0 {
+		o.Quote = '"'
+	}
+	if o.LineTerminator == "" {
+		o.LineTerminator = "\r\n"
+	}
+	return o
+}
+
+// buildDelimited sets up the header and row terminator for FormatCSV and
+// FormatTSV. The byte order mark, if any, is written by WriteHeader even
+// with NoHeader. Columns keep their writers but only their values are used.
+func (p *Program[T]) buildDelimited() {
+	p.csv = csvConfig(p.opts.Format, p.opts.CSV)
+	p.newline = []byte(p.csv.LineTerminator)
+	if p.csv.BOM {
+		p.head = []byte(utf8BOM)
+	}
+
+	if p.opts.NoHeader {
+		return
+	}
+	for i, f := range p.shown {
+		if i > 0 {
+			p.header = utf8.AppendRune(p.header, p.csv.Delimiter)
+		}
+		name := f.Display
+		if p.csv.HeaderNames {
+			name = f.Name
+		}
+		p.header = appendCSVField(p.header, name, p.csv.Delimiter, p.csv.Quote)
+	}
+	p.head = append(p.head, p.header...)
+	p.head = append(p.head, p.newline...)
+}
+
+// appendDelimitedRow appends a CSV or TSV record, without the terminator.
+func (p *Program[T]) appendDelimitedRow(v *T, tmp, line *[]byte) {
+	delim, quote := p.csv.Delimiter, p.csv.Quote
+	for i := range p.columns {
+		if i > 0 {
+			*line = utf8.AppendRune(*line, delim)
+		}
+		start := len(*tmp)
+		*tmp = p.columns[i].value(*tmp, v)
+		*line = appendCSVField(*line, (*tmp)[start:], delim, quote)
+		*tmp = (*tmp)[:start]
+	}
+}
+
+// appendCSVField appends val to dst, enclosed in quote characters if it
+// contains the delimiter, a quote, a line break or a leading space.
+// Quotes inside the value are doubled.
+func appendCSVField[S text](dst []byte, val S, delim, quote rune) []byte {
+	if !csvNeedsQuotes(val, delim, quote) {
+		return append(dst, val...)
+	}
+
+	dst = utf8.AppendRune(dst, quote)
+	for i := 0; i < len(val); {
+		r, size := decodeRune(val, i)
+		if r == quote {
+			dst = utf8.AppendRune(dst, quote)
+		}
+		dst = append(dst, val[i:i+size]...)
+		i += size
+	}
+	return utf8.AppendRune(dst, quote)
+}
+
+// csvNeedsQuotes reports whether val must be quoted.
+func csvNeedsQuotes[S text](val S, delim, quote rune) bool {
+	if len(val) == 0 {
+		return false
+	}
+	if val[0] == ' ' || val[0] == '\t' {
+		return true
+	}
+	for i := 0; i < len(val); {
+		r, size := decodeRune(val, i)
+		if r == delim || r == quote || r == '\n' || r == '\r' {
+			return true
+		}
+		i += size
+	}
+	return false
+}
@@ -0,0 +1,294 @@
+package colprint
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SpecError describes a problem with one token of a field specification.
+type SpecError struct {
+	// Spec is the specification the error was found in
+	Spec string
+
+	// Pos is the byte offset of Token in Spec
+	Pos int
+
+	// Token is the offending token, or the part of it that is wrong
+	Token string
+
+	// Msg says what is wrong
+	Msg string
+
+	// Suggestions are close matches for an unknown name, best first
+	Suggestions []string
+
+	// Err is the underlying error, such as the SpecErrors of a view or
+	// collection that Token expands to. Its positions are relative to the
+	// spec it was found in, not to Spec.
+	Err error
+}
+
+// Error returns the message, the offset and any suggestions, as in
+// `unknown field "nmae" at offset 4; did you mean "name"?`.
+func (e *SpecError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Msg)
+	if e.Spec != "" {
+		fmt.Fprintf(&b, " at offset %d", e.Pos)
+	}
+	if len(e.Suggestions) > 0 {
+		b.WriteString("; did you mean ")
+		for i, s := range e.Suggestions {
+			if i > 0 {
+				b.WriteString(" or ")
+			}
+			fmt.Fprintf(&b, "%q", s)
+		}
+		b.WriteByte('?')
+	}
+	return b.String()
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *SpecError) Unwrap() error {
+	return e.Err
+}
+
+// Caret returns the spec with a caret line under the offending token,
+// ready to print below an error message:
+//
+//	pid,nmae,rss
+//	    ^~~~
+func (e *SpecError) Caret() string {
+	pos := min(max(e.Pos, 0), len(e.Spec))
+	var b strings.Builder
+	b.WriteString(e.Spec)
+	b.WriteByte('\n')
+	b.WriteString(strings.Repeat(" ", textWidth(e.Spec[:pos])))
+	b.WriteByte('^')
+	if n := textWidth(e.Token); n > 1 {
+		b.WriteString(strings.Repeat("~", n-1))
+	}
+	return b.String()
+}
+
+// SpecErrors lists every problem found in a specification; Compile
+// reports all bad tokens at once rather than stopping at the first. Use
+// errors.As to get the first *SpecError, or range over the list.
+type SpecErrors []*SpecError
+
+// Error joins the messages of all errors.
+func (l SpecErrors) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual errors, for errors.As and errors.Is.
+func (l SpecErrors) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, e := range l {
+		errs[i] = e
+	}
+	return errs
+}
+
+// ValidationError describes a problem with a field, view or collection
+// definition, as found by Registry.Validate.
+type ValidationError struct {
+	// Registry is the name of the registry holding the definition, or ""
+	// for the root registry
+	Registry string
+
+	// Name is the field name, or "@name" for a view or collection
+	Name string
+
+	// Msg says what is wrong
+	Msg string
+}
+
+// Error returns the message with the name and registry, as in
+// `field "age": width 0 must be positive`.
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	if e.Registry != "" {
+		b.WriteString(e.Registry)
+		b.WriteString(": ")
+	}
+	if strings.HasPrefix(e.Name, "@") {
+		b.WriteString(e.Name)
+	} else {
+		fmt.Fprintf(&b, "field %q", e.Name)
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Msg)
+	return b.String()
+}
+
+// ValidationErrors lists every problem Registry.Validate found.
+type ValidationErrors []*ValidationError
+
+// Error joins the messages of all errors.
+func (l ValidationErrors) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual errors, for errors.As and errors.Is.
+func (l ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, e := range l {
+		errs[i] = e
+	}
+	return errs
+}
+
+// SpecWarning reports something in a spec that compiled but should be
+// changed, such as a deprecated field. See Program.Warnings.
+type SpecWarning struct {
+	// Spec is the specification the warning was found in
+	Spec string
+
+	// Pos is the byte offset of Token in Spec
+	Pos int
+
+	// Token is the name that caused the warning
+	Token string
+
+	// Msg says what is wrong
+	Msg string
+
+	// Replacement is the field used instead, if any
+	Replacement string
+}
+
+// String returns the message and the offset.
+func (w SpecWarning) String() string {
+	return fmt.Sprintf("%s at offset %d", w.Msg, w.Pos)
+}
+
+// errUnknownRef is returned by expandRef for a name that is neither a
+// view nor a collection.
+var errUnknownRef = errors.New("unknown collection")
+
+// refError is an error found while expanding @Name. The positions in Err
+// are relative to the spec @Name expands to.
+type refError struct {
+	Name string
+	Err  error
+}
+
+func (e *refError) Error() string {
+	return "expanding @" + e.Name + ": " + e.Err.Error()
+}
+
+func (e *refError) Unwrap() error {
+	return e.Err
+}
+
+// refMessage returns the message of err, an error from expanding a
+// reference, without the offsets of its SpecErrors, which would not be
+// relative to the spec the reference appears in.
+func refMessage(err error) string {
+	switch e := err.(type) {
+	case *refError:
+		return "expanding @" + e.Name + ": " + refMessage(e.Err)
+	case SpecErrors:
+		msgs := make([]string, len(e))
+		for i, se := range e {
+			msgs[i] = se.Msg
+		}
+		return strings.Join(msgs, "; ")
+	case *SpecError:
+		return e.Msg
+	}
+	return err.Error()
+}
+
+// maxSuggestions is the most suggestions a SpecError carries.
+const maxSuggestions = 3
+
+// suggest returns the candidates closest to name by edit distance,
+// ignoring case, best first. Candidates too far from name to be a typo are
+// left out.
+func suggest(name string, candidates []string) []string {
+	type match struct {
+		name string
+		dist int
+	}
+	name = strings.ToLower(name)
+	limit := 1
+	switch n := len(name); {
+	case n > 6:
+		limit = 3
+	case n > 3:
+		limit = 2
+	}
+
+	var matches []match
+	for _, c := range candidates {
+		d := editDistance(name, strings.ToLower(strings.TrimPrefix(c, "@")))
+		if d <= limit && d < len(name) {
+			matches = append(matches, match{c, d})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].dist < matches[j].dist
+	})
+
+	var out []string
+	for _, m := range matches {
+		if len(out) == maxSuggestions {
+			break
+		}
+		if !containsString(out, m.name) {
+			out = append(out, m.name)
+		}
+	}
+	return out
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b: the number of single-byte insertions, deletions, substitutions and
+// adjacent transpositions needed to turn one into the other.
+func editDistance(a, b string) int {
+	// Three rows of the dynamic programming table are enough
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// containsString reports whether list includes s.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
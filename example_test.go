@@ -92,6 +92,48 @@ func Example_widthOverride() {
 	// Alexandria            33
 }
 
+// Example_alignment shows right and decimal-point alignment of numbers.
+func Example_alignment() {
+	reg := colprint.NewRegistry[Person]()
+
+	reg.Field("name", "Name", "Person's name").
+		Width(8).
+		String(func(p *Person) string { return p.Name }).
+		Register()
+
+	reg.Field("age", "Age", "Age in years").
+		Width(4).
+		Align(colprint.AlignRight).
+		Int(func(p *Person) int { return p.Age }).
+		Register()
+
+	reg.Field("height", "Height", "Height in cm").
+		Width(8).
+		Align(colprint.AlignDecimal).
+		Float(1, func(p *Person) float64 { return p.Height }).
+		Register()
+
+	prog, _ := colprint.Compile(reg, "name,age,height")
+
+	line := make([]byte, 0, 128)
+	tmp := make([]byte, 0, 32)
+
+	prog.WriteHeader(os.Stdout, &line)
+
+	people := []Person{
+		{Name: "Alice", Age: 30, Height: 165.5},
+		{Name: "Tom", Age: 7, Height: 92},
+	}
+	for i := range people {
+		prog.WriteRow(os.Stdout, &people[i], &tmp, &line)
+	}
+
+	// Output:
+	// Name       Age    Height
+	// Alice       30     165.5
+	// Tom          7      92.0
+}
+
 // Example_custom demonstrates custom formatters for complex types.
 func Example_custom() {
 	reg := colprint.NewRegistry[Person]()
@@ -176,11 +218,10 @@ func Example_collections() {
 	// Carol       45    2
 }
 
-// Example_csv demonstrates CSV-style output with custom separator.
+// Example_csv demonstrates RFC 4180 CSV output.
 func Example_csv() {
 	reg := colprint.NewRegistry[Person]()
 
-	// For CSV output, width determines max field size
 	reg.Field("name", "Name", "Person's name").
 		Width(5).
 		String(func(p *Person) string { return p.Name }).
@@ -191,7 +232,90 @@ func Example_csv() {
 		Int(func(p *Person) int { return p.Age }).
 		Register()
 
-	// For CSV output, use NoPadding option
+	// Values are quoted as needed and never truncated to Width
+	prog, _ := colprint.CompileWithOptions(reg, "name,age", colprint.Options{
+		Format: colprint.FormatCSV,
+		CSV: colprint.CSVOptions{
+			LineTerminator: "\n",
+			HeaderNames:    true,
+		},
+	})
+
+	line := make([]byte, 0, 128)
+	tmp := make([]byte, 0, 32)
+
+	prog.WriteHeader(os.Stdout, &line)
+
+	people := []Person{
+		{Name: "Smith, Alice", Age: 30},
+		{Name: `Bob "The Builder"`, Age: 25},
+	}
+
+	for i := range people {
+		prog.WriteRow(os.Stdout, &people[i], &tmp, &line)
+	}
+
+	// Output:
+	// name,age
+	// "Smith, Alice",30
+	// "Bob ""The Builder""",25
+}
+
+func Example_json() {
+	reg := colprint.NewRegistry[Person]()
+
+	reg.Field("name", "Name", "Person's name").
+		String(func(p *Person) string { return p.Name }).
+		Register()
+
+	reg.Field("age", "Age", "Age in years").
+		Int(func(p *Person) int { return p.Age }).
+		Register()
+
+	prog, _ := colprint.CompileWithOptions(reg, "name,age", colprint.Options{
+		Format: colprint.FormatJSON,
+	})
+
+	line := make([]byte, 0, 128)
+	tmp := make([]byte, 0, 32)
+
+	prog.WriteHeader(os.Stdout, &line)
+
+	people := []Person{
+		{Name: "Alice", Age: 30},
+		{Name: `Bob "The Builder"`, Age: 25},
+	}
+
+	for i := range people {
+		prog.WriteRow(os.Stdout, &people[i], &tmp, &line)
+	}
+	prog.WriteFooter(os.Stdout, &line)
+
+	// Output:
+	// [
+	// {"name":"Alice","age":30},
+	// {"name":"Bob \"The Builder\"","age":25}
+	// ]
+}
+
+// Example_separator demonstrates unpadded output with a custom separator.
+//
+// Values are not quoted; use FormatCSV for real CSV files.
+func Example_separator() {
+	reg := colprint.NewRegistry[Person]()
+
+	// Without padding, width is only the maximum field size
+	reg.Field("name", "Name", "Person's name").
+		Width(5).
+		String(func(p *Person) string { return p.Name }).
+		Register()
+
+	reg.Field("age", "Age", "Age in years").
+		Width(3).
+		Int(func(p *Person) int { return p.Age }).
+		Register()
+
+	// Skip padding so values are joined by the separator alone
 	opts := colprint.Options{
 		Separator: ",",
 		NoPadding: true,
@@ -264,28 +388,73 @@ func Example_streaming() {
 	// Carol       35
 }
 
+// Example_autoWidth sizes columns from the data instead of fixed widths.
+func Example_autoWidth() {
+	reg := colprint.NewRegistry[Person]()
+
+	reg.Field("name", "Name", "Person's name").
+		String(func(p *Person) string { return p.Name }).
+		Register()
+
+	reg.Field("age", "Age", "Age in years").
+		Align(colprint.AlignRight).
+		Int(func(p *Person) int { return p.Age }).
+		Register()
+
+	prog, _ := colprint.CompileWithOptions(reg, "name,age", colprint.Options{
+		Separator: "  ",
+		AutoWidth: true,
+	})
+
+	people := []Person{
+		{Name: "Alice", Age: 30},
+		{Name: "Maximilian", Age: 102},
+	}
+
+	// Batch mode: measure every row, then print
+	prog.Fit(people)
+
+	line := make([]byte, 0, 128)
+	tmp := make([]byte, 0, 32)
+
+	prog.WriteHeader(os.Stdout, &line)
+	prog.WriteUnderline(os.Stdout, &line)
+	for i := range people {
+		prog.WriteRow(os.Stdout, &people[i], &tmp, &line)
+	}
+
+	// Output:
+	// Name        Age
+	// ----        ---
+	// Alice        30
+	// Maximilian  102
+}
+
 // Example_help demonstrates the help functionality.
 func Example_help() {
 	reg := colprint.NewRegistry[Person]()
 
-	reg.Field("name", "Name", "Person's full name").
+	// Named sub-registries appear as separate help sections
+	basic := colprint.NewRegistryWithName[Person]("Basic")
+	basic.Field("name", "Name", "Person's full name").
 		Width(12).
-		Category("Basic").
 		String(func(p *Person) string { return p.Name }).
 		Register()
 
-	reg.Field("age", "Age", "Age in years").
+	basic.Field("age", "Age", "Age in years").
 		Width(4).
-		Category("Basic").
 		Int(func(p *Person) int { return p.Age }).
 		Register()
 
-	reg.Field("height", "Height", "Height in centimeters").
+	physical := colprint.NewRegistryWithName[Person]("Physical")
+	physical.Field("height", "Height", "Height in centimeters").
 		Width(8).
-		Category("Physical").
 		Float(1, func(p *Person) float64 { return p.Height }).
 		Register()
 
+	reg.AddRegistry(basic)
+	reg.AddRegistry(physical)
+
 	// Print help
 	var buf bytes.Buffer
 	reg.PrintHelp(&buf, "")
@@ -297,8 +466,8 @@ func Example_help() {
 	//
 	// Basic:
 	//   Field  Display  Description
-	//   age    Age      Age in years
 	//   name   Name     Person's full name
+	//   age    Age      Age in years
 	//
 	// Physical:
 	//   Field   Display  Description
@@ -0,0 +1,189 @@
+package colprint
+
+import (
+	"math"
+	"strconv"
+)
+
+// formatSI is the Float format hint that scales values with SI prefixes.
+const formatSI = "%si"
+
+// floatStyle is the notation a Float field is written in.
+type floatStyle int
+
+const (
+	floatFixed    floatStyle = iota // "%f": Precision fraction digits
+	floatExp                        // "%e": d.ddde+XX, Precision fraction digits
+	floatEng                        // "%eng": like %e, with the exponent a multiple of 3
+	floatShortest                   // "%g": the fewest digits that read back exactly
+	floatSig                        // "%sig": Precision significant digits
+	floatSI                         // "%si": scaled by an SI prefix
+)
+
+// floatStyles maps the Float format hints to their styles.
+var floatStyles = map[string]floatStyle{
+	"":       floatFixed,
+	"%f":     floatFixed,
+	"%e":     floatExp,
+	"%eng":   floatEng,
+	"%g":     floatShortest,
+	"%sig":   floatSig,
+	formatSI: floatSI,
+}
+
+// NonFinite sets how Float fields write values that are not finite
+// numbers. Empty strings keep the strconv spelling: "NaN", "+Inf" and
+// "-Inf". JSON output always writes null.
+type NonFinite struct {
+	NaN    string
+	PosInf string
+	NegInf string
+}
+
+// floatFormat is the formatting of a Float field.
+type floatFormat struct {
+	style     floatStyle
+	prec      int    // fraction or significant digits
+	unit      string // appended by floatSI
+	nonFinite NonFinite
+}
+
+// newFloatFormat returns the formatting of f. A negative Precision means
+// two digits.
+func newFloatFormat[T any](f Field[T], opts *Options) floatFormat {
+	ff := floatFormat{style: floatStyles[f.Format], prec: f.Precision, unit: f.Unit}
+	if ff.prec < 0 {
+		ff.prec = 2
+	}
+	if opts != nil {
+		ff.nonFinite = opts.NonFinite
+	}
+	return ff
+}
+
+// append appends v to dst.
+func (ff *floatFormat) append(dst []byte, v float64) []byte {
+	switch {
+	case math.IsNaN(v) && ff.nonFinite.NaN != "":
+		return append(dst, ff.nonFinite.NaN...)
+	case math.IsInf(v, 1) && ff.nonFinite.PosInf != "":
+		return append(dst, ff.nonFinite.PosInf...)
+	case math.IsInf(v, -1) && ff.nonFinite.NegInf != "":
+		return append(dst, ff.nonFinite.NegInf...)
+	}
+
+	switch ff.style {
+	case floatExp:
+		return strconv.AppendFloat(dst, v, 'e', ff.prec, 64)
+	case floatEng:
+		return appendEng(dst, v, ff.prec)
+	case floatShortest:
+		return strconv.AppendFloat(dst, v, 'g', -1, 64)
+	case floatSig:
+		return appendSig(dst, v, ff.prec)
+	case floatSI:
+		return appendSI(dst, v, ff.prec, ff.unit)
+	}
+	return strconv.AppendFloat(dst, v, 'f', ff.prec, 64)
+}
+
+// appendEng appends v in engineering notation: like strconv's 'e' format
+// with prec fraction digits, but with one to three integer digits so the
+// exponent is a multiple of 3, as in "12.50e+03".
+//
+// The number is written past the end of dst and rewritten in place, so
+// a buffer that has grown once is reused without allocating.
+func appendEng(dst []byte, v float64, prec int) []byte {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return strconv.AppendFloat(dst, v, 'e', prec, 64)
+	}
+	start := len(dst)
+	dst = strconv.AppendFloat(dst, v, 'e', -1, 64)
+	shift := mod3(exponent(dst[start:]))
+
+	// Rounding may carry into the next power of ten, leaving a mantissa
+	// of 1 and zeros that needs more or fewer digits than asked for
+	dst = strconv.AppendFloat(dst[:start], v, 'e', prec+shift, 64)
+	end := len(dst)
+	num := dst[start:end]
+	if num[0] == '-' {
+		dst = append(dst, '-')
+		num = num[1:]
+	}
+	exp := exponent(num)
+	shift = mod3(exp)
+	m := 0 // end of the mantissa
+	for num[m] != 'e' {
+		m++
+	}
+	for i := range 1 + shift + prec {
+		if i == 1+shift {
+			dst = append(dst, '.')
+		}
+		d := byte('0')
+		if i == 0 {
+			d = num[0]
+		} else if i+1 < m {
+			d = num[i+1]
+		}
+		dst = append(dst, d)
+	}
+	dst = append(dst, 'e')
+	dst = appendExponent(dst, exp-shift)
+
+	k := copy(dst[start:], dst[end:])
+	return dst[:start+k]
+}
+
+// appendSig appends v rounded to n significant digits, keeping trailing
+// zeros. Like C's %g, values with an exponent below -4 or of n and above
+// are written in 'e' notation.
+func appendSig(dst []byte, v float64, n int) []byte {
+	n = max(n, 1)
+	start := len(dst)
+	dst = strconv.AppendFloat(dst, v, 'e', n-1, 64)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return dst
+	}
+	exp := exponent(dst[start:])
+	if exp < -4 || exp >= n {
+		return dst
+	}
+	return strconv.AppendFloat(dst[:start], v, 'f', n-1-exp, 64)
+}
+
+// exponent returns the exponent of a number in strconv's 'e' notation.
+func exponent(num []byte) int {
+	i := len(num) - 1
+	for i > 0 && num[i] != 'e' {
+		i--
+	}
+	exp := 0
+	for _, c := range num[i+2:] {
+		exp = exp*10 + int(c-'0')
+	}
+	if num[i+1] == '-' {
+		return -exp
+	}
+	return exp
+}
+
+// appendExponent appends exp with a sign and at least two digits, as
+// strconv does.
+func appendExponent(dst []byte, exp int) []byte {
+	if exp < 0 {
+		dst = append(dst, '-')
+		exp = -exp
+	} else {
+		dst = append(dst, '+')
+	}
+	if exp < 10 {
+		dst = append(dst, '0')
+	}
+	return strconv.AppendInt(dst, int64(exp), 10)
+}
+
+// mod3 returns n modulo 3, from 0 to 2 for negative n too.
+func mod3(n int) int {
+	return (n%3 + 3) % 3
+}
@@ -1,49 +1,157 @@
 package colprint
 
-// padLeft appends s to dst, padding or truncating to width characters.
-// All padding is on the right (left-aligned text).
-func padLeft(dst []byte, s string, width int) []byte {
-	return padBytesLeft(dst, []byte(s), width)
+// text is satisfied by both string and []byte, letting the padding helpers
+// work on either without a conversion (and the allocation it may cost).
+type text interface {
+	~string | ~[]byte
 }
 
-// padBytesLeft appends val to dst, padding or truncating to width.
-// This operates on byte slices for efficiency.
-func padBytesLeft(dst, val []byte, width int) []byte {
-	// Truncate if too long
-	if len(val) > width {
-		return append(dst, val[:width]...)
+// ellipsis marks where a value was shortened.
+const ellipsis = "…"
+
+// cell describes how values are laid out within a column. It is resolved
+// once at compile time and captured by the column's writer closure.
+type cell struct {
+	width int
+	align Align
+	// frac is the number of cells AlignDecimal reserves right of the
+	// integer part: the '.' plus the fraction digits.
+	frac int
+	// point is the decimal separator AlignDecimal looks for ("" is '.')
+	point string
+	// acct is set when negative values end in ')', which frac includes
+	acct  bool
+	trunc Truncate
+	// trim omits trailing padding (used for the last column).
+	trim bool
+}
+
+// padCell appends val to dst, aligned within c.width display cells.
+//
+// Widths are measured in terminal cells (see textWidth), so wide and
+// combining characters pad correctly. Values wider than the column are
+// shortened according to c.trunc, always on a grapheme cluster boundary.
+func padCell[S text](dst []byte, val S, c *cell) []byte {
+	n := textWidth(val)
+	if n > c.width {
+		return truncateCell(dst, val, n, c)
 	}
 
-	// Append value
+	left := 0
+	switch c.align {
+	case AlignRight:
+		left = c.width - n
+	case AlignCenter:
+		left = (c.width - n) / 2
+	case AlignDecimal:
+		left = decimalOffset(val, n, c)
+	}
+
+	dst = appendSpaces(dst, left)
 	dst = append(dst, val...)
+	if !c.trim {
+		dst = appendSpaces(dst, c.width-left-n)
+	}
+	return dst
+}
 
-	// Pad with spaces on the right
-	for i := len(val); i < width; i++ {
-		dst = append(dst, ' ')
+// truncateCell appends val, which is n cells wide and does not fit in the
+// column, shortened according to c.trunc.
+func truncateCell[S text](dst []byte, val S, n int, c *cell) []byte {
+	width := c.width
+	w := 0
+
+	switch c.trunc {
+	case TruncateOverflow:
+		return append(dst, val...)
+
+	case TruncateHash:
+		for i := 0; i < width; i++ {
+			dst = append(dst, '#')
+		}
+		return dst
+
+	case TruncateEllipsis:
+		if width < 1 {
+			break
+		}
+		end, hw := truncateWidth(val, width-1)
+		dst = append(dst, val[:end]...)
+		dst = append(dst, ellipsis...)
+		w = hw + 1
+
+	case TruncateLeading:
+		if width < 1 {
+			break
+		}
+		start, tw := skipWidth(val, n, width-1)
+		dst = append(dst, ellipsis...)
+		dst = append(dst, val[start:]...)
+		w = tw + 1
+
+	case TruncateMiddle:
+		if width < 1 {
+			break
+		}
+		end, hw := truncateWidth(val, width/2)
+		start, tw := skipWidth(val, n, width-1-hw)
+		dst = append(dst, val[:end]...)
+		dst = append(dst, ellipsis...)
+		dst = append(dst, val[start:]...)
+		w = hw + 1 + tw
+
+	default:
+		end, cw := truncateWidth(val, width)
+		dst = append(dst, val[:end]...)
+		w = cw
 	}
 
+	// A wide character may leave a cell unfilled
+	if !c.trim {
+		dst = appendSpaces(dst, width-w)
+	}
 	return dst
 }
 
-// Phase 2: Right-alignment functions (to be implemented)
-//
-// func padRight(dst []byte, s string, width int) []byte {
-//     return padBytesRight(dst, []byte(s), width)
-// }
-//
-// func padBytesRight(dst, val []byte, width int) []byte {
-//     // Truncate if too long
-//     if len(val) > width {
-//         return append(dst, val[:width]...)
-//     }
-//
-//     // Pad with spaces on the left
-//     if pad := width - len(val); pad > 0 {
-//         for i := 0; i < pad; i++ {
-//             dst = append(dst, ' ')
-//         }
-//     }
-//
-//     // Append value
-//     return append(dst, val...)
-// }
+// decimalOffset returns the left padding that puts the decimal point of val
+// at cell width-frac. n is the display width of val. Values without the
+// point ("" is '.') are treated as all integer part, but for a closing ')'
+// in accounting columns. The result is clamped so val always fits within
+// width.
+func decimalOffset[S text](val S, n int, c *cell) int {
+	width, frac, point := c.width, c.frac, c.point
+	if point == "" {
+		point = "."
+	}
+	intLen := n
+	if c.acct && len(val) > 0 && val[len(val)-1] == ')' {
+		intLen--
+	}
+	for i := 0; i+len(point) <= len(val); i++ {
+		j := 0
+		for j < len(point) && val[i+j] == point[j] {
+			j++
+		}
+		if j == len(point) {
+			intLen = textWidth(val[:i])
+			break
+		}
+	}
+
+	left := width - frac - intLen
+	if left > width-n {
+		left = width - n
+	}
+	if left < 0 {
+		left = 0
+	}
+	return left
+}
+
+// appendSpaces appends n spaces to dst.
+func appendSpaces(dst []byte, n int) []byte {
+	for i := 0; i < n; i++ {
+		dst = append(dst, ' ')
+	}
+	return dst
+}
@@ -0,0 +1,120 @@
+package colprint
+
+// buildHTML sets up the table framing and per-column tags for FormatHTML.
+//
+// Every cell carries a CSS class derived from its field name (see
+// htmlClass). Cells are aligned by a text-align style taken from the
+// field's alignment; Int and Float columns are right-aligned unless the
+// field asks for centering.
+func (p *Program[T]) buildHTML() {
+	p.htmlCells = make([][]byte, len(p.shown))
+	var th [][]byte
+	for i, f := range p.shown {
+		attrs := append([]byte(` class="`), htmlClass(f.Name)...)
+		attrs = append(attrs, '"')
+		switch htmlAlign(f) {
+		case AlignRight:
+			attrs = append(attrs, ` style="text-align:right"`...)
+		case AlignCenter:
+			attrs = append(attrs, ` style="text-align:center"`...)
+		}
+		p.htmlCells[i] = append(append([]byte("<td"), attrs...), '>')
+
+		tag := append([]byte("<th"), attrs...)
+		if f.Description != "" {
+			tag = append(tag, ` title="`...)
+			tag = appendHTMLEscaped(tag, f.Description)
+			tag = append(tag, '"')
+		}
+		th = append(th, append(tag, '>'))
+	}
+
+	p.head = append(p.head, "<table>\n"...)
+	if !p.opts.NoHeader {
+		p.header = append(p.header, "<tr>"...)
+		for i, f := range p.shown {
+			p.header = append(p.header, th[i]...)
+			p.header = appendHTMLEscaped(p.header, f.Display)
+			p.header = append(p.header, "</th>"...)
+		}
+		p.header = append(p.header, "</tr>"...)
+
+		p.head = append(p.head, "<thead>\n"...)
+		p.head = append(p.head, p.header...)
+		p.head = append(p.head, "\n</thead>\n"...)
+	}
+	p.head = append(p.head, "<tbody>\n"...)
+	p.foot = []byte("</tbody>\n</table>\n")
+}
+
+// htmlAlign returns the alignment of a field's HTML cells.
+func htmlAlign[T any](f Field[T]) Align {
+	switch {
+	case f.Align == AlignDecimal:
+		return AlignRight
+	case f.Align == AlignLeft && isNumeric(f.Kind):
+		return AlignRight
+	}
+	return f.Align
+}
+
+// htmlClass returns the CSS class of a field's cells: "col-" followed by
+// the lowercased field name, with anything other than letters, digits,
+// '-' and '_' replaced by '-'. A field "CPU%" gets class "col-cpu-".
+func htmlClass(name string) []byte {
+	class := []byte("col-")
+	for i := 0; i < len(name); i++ {
+		b := name[i]
+		switch {
+		case b >= 'A' && b <= 'Z':
+			b += 'a' - 'A'
+		case b >= 'a' && b <= 'z', b >= '0' && b <= '9', b == '-', b == '_':
+		default:
+			b = '-'
+		}
+		class = append(class, b)
+	}
+	return class
+}
+
+// appendHTMLRow appends a table row. Values are formatted into tmp and
+// escaped from there.
+func (p *Program[T]) appendHTMLRow(v *T, tmp, line *[]byte) {
+	*line = append(*line, "<tr>"...)
+	for i := range p.columns {
+		*line = append(*line, p.htmlCells[i]...)
+		start := len(*tmp)
+		*tmp = p.columns[i].value(*tmp, v)
+		*line = appendHTMLEscaped(*line, (*tmp)[start:])
+		*tmp = (*tmp)[:start]
+		*line = append(*line, "</td>"...)
+	}
+	*line = append(*line, "</tr>"...)
+}
+
+// appendHTMLEscaped appends s with the characters special in HTML text and
+// attribute values replaced by entities.
+func appendHTMLEscaped[S text](dst []byte, s S) []byte {
+	start := 0
+	for i := 0; i < len(s); i++ {
+		var esc string
+		switch s[i] {
+		case '&':
+			esc = "&amp;"
+		case '<':
+			esc = "&lt;"
+		case '>':
+			esc = "&gt;"
+		case '"':
+			esc = "&#34;"
+		case '\'':
+			esc = "&#39;"
+		default:
+			continue
+		}
+		dst = append(dst, s[start:i]...)
+		dst = append(dst, esc...)
+		start = i + 1
+	}
+	return append(dst, s[start:]...)
+}
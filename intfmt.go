@@ -0,0 +1,122 @@
+package colprint
+
+import (
+	"strconv"
+)
+
+// intFormat is the radix formatting of an integer field, parsed from a
+// format hint of the form %[#][0][digits]verb.
+type intFormat struct {
+	base   int
+	upper  bool // upper-case hex digits
+	prefix bool // 0x, 0b, or 0 for octal
+	digits int  // zero-pad to this many digits
+}
+
+// parseIntFormat parses an integer format hint: "%d" (the default), "%x"
+// or "%X" for hex, "%o" for octal and "%b" for binary. A '#' adds the
+// prefix 0x, 0X, 0b or 0, and a zero followed by a number pads with
+// zeros to that many digits, as in "%#08x".
+func parseIntFormat(format string) (intFormat, bool) {
+	if format == "" {
+		return intFormat{base: 10}, true
+	}
+	if len(format) < 2 || format[0] != '%' {
+		return intFormat{}, false
+	}
+	var f intFormat
+	spec := format[1:]
+	if spec[0] == '#' {
+		f.prefix = true
+		spec = spec[1:]
+	}
+	if len(spec) > 1 && spec[0] == '0' {
+		n, err := strconv.Atoi(spec[1 : len(spec)-1])
+		if err != nil || n <= 0 || n > 64 {
+			return intFormat{}, false
+		}
+		f.digits = n
+		spec = spec[len(spec)-1:]
+	}
+	if len(spec) != 1 {
+		return intFormat{}, false
+	}
+	switch spec[0] {
+	case 'd':
+		f.base = 10
+		if f.prefix {
+			return intFormat{}, false
+		}
+	case 'x':
+		f.base = 16
+	case 'X':
+		f.base, f.upper = 16, true
+	case 'o':
+		f.base = 8
+	case 'b':
+		f.base = 2
+	default:
+		return intFormat{}, false
+	}
+	return f, true
+}
+
+// zeroPadded reports whether f pads decimal numbers with zeros.
+func (f intFormat) zeroPadded() bool {
+	return f.base == 10 && f.digits > 0
+}
+
+// appendInt appends n in the format f.
+func (f intFormat) appendInt(dst []byte, n int64) []byte {
+	if n < 0 {
+		dst = append(dst, '-')
+		// Negating as uint64 also handles math.MinInt64
+		return f.appendUint(dst, -uint64(n))
+	}
+	return f.appendUint(dst, uint64(n))
+}
+
+// appendUint appends u in the format f.
+func (f intFormat) appendUint(dst []byte, u uint64) []byte {
+	if f.base == 10 && f.digits == 0 {
+		return strconv.AppendUint(dst, u, 10)
+	}
+	if f.prefix {
+		switch f.base {
+		case 16:
+			if f.upper {
+				dst = append(dst, "0X"...)
+			} else {
+				dst = append(dst, "0x"...)
+			}
+		case 8:
+			if u != 0 {
+				dst = append(dst, '0')
+			}
+		case 2:
+			dst = append(dst, "0b"...)
+		}
+	}
+
+	start := len(dst)
+	dst = strconv.AppendUint(dst, u, f.base)
+	if n := len(dst) - start; n < f.digits {
+		// Shift the digits right and fill in zeros
+		pad := f.digits - n
+		for range pad {
+			dst = append(dst, '0')
+		}
+		copy(dst[start+pad:], dst[start:start+n])
+		for i := start; i < start+pad; i++ {
+			dst[i] = '0'
+		}
+	}
+	if f.upper {
+		for i := start; i < len(dst); i++ {
+			if c := dst[i]; c >= 'a' && c <= 'f' {
+				dst[i] = c - 'a' + 'A'
+			}
+		}
+	}
+	return dst
+}
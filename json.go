@@ -0,0 +1,193 @@
+package colprint
+
+import (
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// buildJSON sets up the object keys and framing for FormatJSONLines and
+// FormatJSON. Keys are the field names, escaped once here so rows only
+// copy them.
+func (p *Program[T]) buildJSON() {
+	p.jsonKeys = make([][]byte, len(p.shown))
+	for i, f := range p.shown {
+		key := appendJSONString(nil, f.Name)
+		p.jsonKeys[i] = append(key, ':')
+	}
+	if p.opts.Format == FormatJSON {
+		p.head = []byte("[\n")
+		p.foot = []byte("]\n")
+		p.newline = nil
+	}
+}
+
+// appendJSONRow appends a row as a single-line JSON object.
+func (p *Program[T]) appendJSONRow(v *T, tmp, line *[]byte) {
+	*line = append(*line, '{')
+	for i := range p.columns {
+		if i > 0 {
+			*line = append(*line, ',')
+		}
+		*line = append(*line, p.jsonKeys[i]...)
+		start := len(*tmp)
+		*tmp = p.columns[i].json(*tmp, v)
+		*line = append(*line, (*tmp)[start:]...)
+		*tmp = (*tmp)[:start]
+	}
+	*line = append(*line, '}')
+}
+
+// makeJSON returns the function that appends a field's value as JSON.
+// Strings are quoted and escaped, numbers stay numbers, and custom values
+// are strings unless the field is marked RawJSON.
+func makeJSON[T any](f Field[T]) func(dst []byte, v *T) []byte {
+	switch f.Kind {
+	case KindString:
+		return func(dst []byte, v *T) []byte {
+			return appendJSONString(dst, f.GetString(v))
+		}
+	case KindInt:
+		return func(dst []byte, v *T) []byte {
+			return strconv.AppendInt(dst, int64(f.GetInt(v)), 10)
+		}
+	case KindInt64:
+		return func(dst []byte, v *T) []byte {
+			return strconv.AppendInt(dst, f.GetInt64(v), 10)
+		}
+	case KindUint64, KindSize:
+		return func(dst []byte, v *T) []byte {
+			return strconv.AppendUint(dst, f.GetUint64(v), 10)
+		}
+	case KindFloat:
+		return func(dst []byte, v *T) []byte {
+			return appendJSONFloat(dst, f.GetFloat(v))
+		}
+	case KindTime:
+		loc := f.Location
+		return func(dst []byte, v *T) []byte {
+			t := f.GetTime(v)
+			if t.IsZero() {
+				return append(dst, "null"...)
+			}
+			if loc != nil {
+				t = t.In(loc)
+			}
+			dst = append(dst, '"')
+			dst = t.AppendFormat(dst, time.RFC3339Nano)
+			return append(dst, '"')
+		}
+	case KindDuration:
+		return func(dst []byte, v *T) []byte {
+			return appendJSONFloat(dst, f.GetDuration(v).Seconds())
+		}
+	case KindCustom:
+		if f.RawJSON {
+			return func(dst []byte, v *T) []byte {
+				start := len(dst)
+				dst = f.GetCustom(dst, v)
+				if len(dst) == start {
+					dst = append(dst, "null"...)
+				}
+				return dst
+			}
+		}
+		return func(dst []byte, v *T) []byte {
+			// Format the raw value past the end of dst, append its escaped
+			// form after it, then slide that back over the raw value.
+			start := len(dst)
+			dst = f.GetCustom(dst, v)
+			n := len(dst) - start
+			dst = appendJSONString(dst, dst[start:])
+			return append(dst[:start], dst[start+n:]...)
+		}
+	}
+	return func(dst []byte, _ *T) []byte {
+		return append(dst, "null"...)
+	}
+}
+
+// appendJSONString appends s as a quoted JSON string.
+//
+// Quotes, backslashes and control characters are escaped, as are U+2028
+// and U+2029 so the output is also valid JavaScript. Invalid UTF-8 is
+// replaced by U+FFFD.
+func appendJSONString[S text](dst []byte, s S) []byte {
+	const hex = "0123456789abcdef"
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := decodeRune(s, i)
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+// appendJSONFloat appends f as a JSON number, in the shortest form that
+// round-trips, like encoding/json. JSON has no NaN or infinity; those are
+// written as null.
+func appendJSONFloat(dst []byte, f float64) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return append(dst, "null"...)
+	}
+	abs := math.Abs(f)
+	fmt := byte('f')
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		fmt = 'e'
+	}
+	start := len(dst)
+	dst = strconv.AppendFloat(dst, f, fmt, -1, 64)
+	if fmt == 'e' {
+		// Clean up e-09 to e-9
+		n := len(dst) - start
+		if n >= 4 && dst[len(dst)-4] == 'e' && dst[len(dst)-3] == '-' && dst[len(dst)-2] == '0' {
+			dst[len(dst)-2] = dst[len(dst)-1]
+			dst = dst[:len(dst)-1]
+		}
+	}
+	return dst
+}
@@ -0,0 +1,71 @@
+package colprint
+
+// layout fits fields into maxWidth cells, given the width of the column
+// separator. It returns the fields to show, with shrinkable columns
+// narrowed as needed, and the names of the fields it had to drop.
+//
+// Columns are dropped first, lowest Priority first and the rightmost on
+// ties, until the line fits with every shrinkable column at its minimum.
+// The remaining excess is then taken from the widest shrinkable columns
+// one cell at a time, so they end up evenly narrowed. At least one column
+// is always kept.
+func layout[T any](fields []Field[T], sepWidth, maxWidth int) ([]Field[T], []string) {
+	kept := make([]Field[T], len(fields))
+	copy(kept, fields)
+	var dropped []string
+
+	for len(kept) > 1 && lineWidth(kept, sepWidth, true) > maxWidth {
+		idx := 0
+		for i := range kept {
+			if kept[i].Priority <= kept[idx].Priority {
+				idx = i
+			}
+		}
+		dropped = append(dropped, kept[idx].Name)
+		kept = append(kept[:idx], kept[idx+1:]...)
+	}
+
+	for excess := lineWidth(kept, sepWidth, false) - maxWidth; excess > 0; excess-- {
+		widest := -1
+		for i := range kept {
+			if kept[i].Width > shrinkFloor(kept[i]) && (widest < 0 || kept[i].Width > kept[widest].Width) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		kept[widest].Width--
+	}
+
+	return kept, dropped
+}
+
+// lineWidth returns the width of a line of fields, with shrinkable columns
+// at their minimum when shrunk is set.
+func lineWidth[T any](fields []Field[T], sepWidth int, shrunk bool) int {
+	total := sepWidth * (len(fields) - 1)
+	for _, f := range fields {
+		if shrunk {
+			total += shrinkFloor(f)
+		} else {
+			total += f.Width
+		}
+	}
+	return total
+}
+
+// shrinkFloor returns the narrowest a column may become: its current width
+// unless it is shrinkable, otherwise MinWidth or, when that is not set,
+// the width of its header.
+func shrinkFloor[T any](f Field[T]) int {
+	if !f.Shrinkable {
+		return f.Width
+	}
+	floor := f.MinWidth
+	if floor <= 0 {
+		floor = textWidth(f.Display)
+	}
+	floor = max(floor, 1)
+	return min(floor, f.Width)
+}
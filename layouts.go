@@ -0,0 +1,110 @@
+package colprint
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Layout is a named spec, such as a column preset a user saved. Loaded
+// into a registry, it becomes a view referenced as @name.
+type Layout struct {
+	Name string `json:"name"`
+	Spec string `json:"spec"`
+}
+
+// LayoutFormat selects how ReadLayouts and WriteLayouts store layouts.
+type LayoutFormat int
+
+const (
+	// LayoutText stores one layout per line, written like an inline
+	// definition: "@mine=pid,cmd,rss:12". Blank lines and lines starting
+	// with '#' are skipped; the '@' is optional.
+	LayoutText LayoutFormat = iota
+	// LayoutJSON stores a JSON array of {"name": ..., "spec": ...} objects.
+	LayoutJSON
+)
+
+// ReadLayouts reads layouts stored by WriteLayouts. The layouts are not
+// checked against a registry; see Registry.LoadLayouts.
+func ReadLayouts(r io.Reader, format LayoutFormat) ([]Layout, error) {
+	if format == LayoutJSON {
+		var layouts []Layout
+		if err := json.NewDecoder(r).Decode(&layouts); err != nil {
+			return nil, fmt.Errorf("reading layouts: %w", err)
+		}
+		return layouts, nil
+	}
+
+	var layouts []Layout
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, spec, ok := strings.Cut(strings.TrimPrefix(line, "@"), "=")
+		if !ok {
+			return nil, fmt.Errorf("reading layouts: line %d: want @name=spec", n)
+		}
+		layouts = append(layouts, Layout{Name: strings.TrimSpace(name), Spec: strings.TrimSpace(spec)})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading layouts: %w", err)
+	}
+	return layouts, nil
+}
+
+// WriteLayouts writes layouts to w in the given format.
+func WriteLayouts(w io.Writer, layouts []Layout, format LayoutFormat) error {
+	if format == LayoutJSON {
+		if layouts == nil {
+			layouts = []Layout{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(layouts)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, l := range layouts {
+		if strings.ContainsAny(l.Spec, "\n;") {
+			return fmt.Errorf("layout %q: spec cannot be stored as text", l.Name)
+		}
+		fmt.Fprintf(bw, "@%s=%s\n", l.Name, l.Spec)
+	}
+	return bw.Flush()
+}
+
+// AddLayouts checks each layout against the registry and defines the valid
+// ones as views, in order, so a layout may build on the ones before it. A
+// layout replaces any view of the same name. The problems found are
+// returned together.
+func (r *Registry[T]) AddLayouts(layouts ...Layout) error {
+	var errs []error
+	for _, l := range layouts {
+		if !validRefName(l.Name) {
+			errs = append(errs, fmt.Errorf("layout %q: invalid name", l.Name))
+			continue
+		}
+		if _, _, err := parseSpec(r, l.Spec, nil); err != nil {
+			errs = append(errs, fmt.Errorf("layout %q: %w", l.Name, err))
+			continue
+		}
+		r.DefineView(l.Name, "", l.Spec)
+	}
+	return errors.Join(errs...)
+}
+
+// LoadLayouts reads layouts from rd and adds them to the registry with
+// AddLayouts.
+func (r *Registry[T]) LoadLayouts(rd io.Reader, format LayoutFormat) error {
+	layouts, err := ReadLayouts(rd, format)
+	if err != nil {
+		return err
+	}
+	return r.AddLayouts(layouts...)
+}
@@ -0,0 +1,127 @@
+package colprint
+
+// buildMarkdown sets up the header and delimiter row for FormatMarkdown.
+//
+// Every cell is framed by pipes. Unless Options.NoPadding is set, cells are
+// padded to the column width, or to the header when it is wider, so the
+// raw text lines up; values that do not fit widen their row rather than
+// being truncated.
+func (p *Program[T]) buildMarkdown() {
+	p.mdCells = make([]cell, len(p.shown))
+	for i, f := range p.shown {
+		c := cell{align: f.Align, frac: decimalFrac(f), trunc: TruncateOverflow}
+		if nf, ok := fieldNumberFormat(f, &p.opts); ok {
+			c.setNumber(&nf)
+		}
+		if !p.opts.NoPadding {
+			c.width = max(f.Width, textWidth(f.Display), 3)
+		}
+		p.mdCells[i] = c
+	}
+
+	if p.opts.NoHeader {
+		return
+	}
+	p.header = append(p.header, '|')
+	for i, f := range p.shown {
+		c := p.mdCells[i]
+		if c.align == AlignDecimal {
+			c.align = AlignRight
+		}
+		p.header = append(p.header, ' ')
+		p.header = appendMarkdownCell(p.header, f.Display, &c)
+		p.header = append(p.header, " |"...)
+	}
+	p.underline = append(p.underline, '|')
+	for i, c := range p.mdCells {
+		n := 0
+		if c.width > 0 {
+			n = c.width + 2
+		}
+		p.underline = appendMarkdownDelimiter(p.underline, p.shown[i].Align, n)
+	}
+
+	p.head = append(p.head, p.header...)
+	p.head = append(p.head, '\n')
+	p.head = append(p.head, p.underline...)
+	p.head = append(p.head, '\n')
+}
+
+// appendMarkdownDelimiter appends one cell of the delimiter row, with
+// colons marking the alignment. A padded cell spans n characters; a compact
+// one (n == 0) gets three dashes.
+func appendMarkdownDelimiter(dst []byte, align Align, n int) []byte {
+	left, right := false, false
+	switch align {
+	case AlignRight, AlignDecimal:
+		right = true
+	case AlignCenter:
+		left, right = true, true
+	}
+
+	dashes := 3
+	if n > 0 {
+		dashes = n
+		if left {
+			dashes--
+		}
+		if right {
+			dashes--
+		}
+	}
+
+	if left {
+		dst = append(dst, ':')
+	}
+	for i := 0; i < dashes; i++ {
+		dst = append(dst, '-')
+	}
+	if right {
+		dst = append(dst, ':')
+	}
+	return append(dst, '|')
+}
+
+// appendMarkdownRow appends a table row. Each value is formatted into tmp,
+// escaped after it, and padded from there.
+func (p *Program[T]) appendMarkdownRow(v *T, tmp, line *[]byte) {
+	*line = append(*line, '|')
+	for i := range p.columns {
+		start := len(*tmp)
+		*tmp = p.columns[i].value(*tmp, v)
+		mid := len(*tmp)
+		*tmp = appendMarkdownEscaped(*tmp, (*tmp)[start:mid])
+
+		*line = append(*line, ' ')
+		*line = padCell(*line, (*tmp)[mid:], &p.mdCells[i])
+		*line = append(*line, " |"...)
+		*tmp = (*tmp)[:start]
+	}
+}
+
+// appendMarkdownCell appends s escaped and padded as a Markdown cell.
+func appendMarkdownCell(dst []byte, s string, c *cell) []byte {
+	return padCell(dst, appendMarkdownEscaped(nil, s), c)
+}
+
+// appendMarkdownEscaped appends s with the characters that would break a
+// table cell escaped: '|' and '\' get a backslash, and line breaks become
+// <br> since a row must stay on one line.
+func appendMarkdownEscaped[S text](dst []byte, s S) []byte {
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; b {
+		case '|', '\\':
+			dst = append(dst, '\\', b)
+		case '\n':
+			dst = append(dst, "<br>"...)
+		case '\r':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				continue
+			}
+			dst = append(dst, "<br>"...)
+		default:
+			dst = append(dst, b)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,189 @@
+package colprint
+
+// NumberFormat groups the digits of Int and Float fields and chooses their
+// separators. The zero value writes numbers as strconv does.
+//
+// It applies to decimal integers and to fixed, exponent and shortest
+// floats; radix formats such as "%x" and SI-scaled floats are left alone.
+// Zero-padded integers, as in "%08d", are not grouped.
+type NumberFormat struct {
+	// Group is inserted between digit groups of the integer part, as in
+	// 1,234,567 ("" disables grouping)
+	Group string
+
+	// Decimal replaces the decimal point (default: ".")
+	Decimal string
+
+	// Indian groups the last three integer digits, then pairs of digits,
+	// as in the lakh and crore grouping 12,34,56,789
+	Indian bool
+
+	// Accounting writes negative numbers in parentheses instead of with a
+	// minus sign, as in (1,234.50)
+	Accounting bool
+}
+
+// Number format presets for common locales.
+var (
+	NumberEN = NumberFormat{Group: ",", Decimal: "."}
+	NumberDE = NumberFormat{Group: ".", Decimal: ","}
+	NumberFR = NumberFormat{Group: "\u202f", Decimal: ","}
+	NumberIN = NumberFormat{Group: ",", Decimal: ".", Indian: true}
+)
+
+// numberLocales maps the locale keywords accepted in a field token to
+// their presets. It holds copies, so changes to the exported presets do
+// not alter what the keywords select.
+var numberLocales = map[string]NumberFormat{
+	"en": NumberEN,
+	"de": NumberDE,
+	"fr": NumberFR,
+	"in": NumberIN,
+}
+
+// enabled reports whether nf changes anything strconv writes.
+func (nf *NumberFormat) enabled() bool {
+	return nf.Group != "" || nf.point() != "." || nf.Accounting
+}
+
+// point returns the decimal separator.
+func (nf *NumberFormat) point() string {
+	if nf.Decimal == "" {
+		return "."
+	}
+	return nf.Decimal
+}
+
+// apply rewrites the number that dst[start:] holds, as strconv writes it,
+// in place: "-1234567.5" becomes "(1,234,567.5)" and so on. Text after
+// the fraction, such as an exponent, is kept as it is.
+//
+// The result is built past the end of dst and copied back, so a buffer
+// that has grown once is reused without allocating.
+func (nf *NumberFormat) apply(dst []byte, start int) []byte {
+	end := len(dst)
+	num := dst[start:end]
+	neg := len(num) > 0 && num[0] == '-'
+	if neg {
+		num = num[1:]
+	}
+	n := 0
+	for n < len(num) && isDigit(num[n]) {
+		n++
+	}
+
+	if neg {
+		if nf.Accounting {
+			dst = append(dst, '(')
+		} else {
+			dst = append(dst, '-')
+		}
+	}
+	for i := range n {
+		if i > 0 && nf.Group != "" && nf.groupsBefore(n-i) {
+			dst = append(dst, nf.Group...)
+		}
+		dst = append(dst, num[i])
+	}
+	rest := num[n:]
+	if len(rest) > 0 && rest[0] == '.' {
+		dst = append(dst, nf.point()...)
+		rest = rest[1:]
+	}
+	dst = append(dst, rest...)
+	if neg && nf.Accounting {
+		dst = append(dst, ')')
+	}
+
+	k := copy(dst[start:], dst[end:])
+	return dst[:start+k]
+}
+
+// groupsBefore reports whether a group separator goes in front of the
+// integer digit that has left digits from it to the decimal point.
+func (nf *NumberFormat) groupsBefore(left int) bool {
+	if nf.Indian && left > 3 {
+		return left%2 == 1
+	}
+	return left%3 == 0
+}
+
+// setNumber makes c find the decimal separator of nf. With AlignDecimal
+// and accounting negatives, it also reserves a cell for the closing ')',
+// so positive values get a trailing pad and the points line up.
+func (c *cell) setNumber(nf *NumberFormat) {
+	c.point = nf.point()
+	if nf.Accounting && c.align == AlignDecimal {
+		c.frac++
+		c.acct = true
+	}
+}
+
+// fieldNumberFormat returns the number format of f: its own, else the one
+// in opts. ok is false when f is not a number it applies to, or the format
+// changes nothing. Zero-padded integers keep their digits ungrouped.
+func fieldNumberFormat[T any](f Field[T], opts *Options) (nf NumberFormat, ok bool) {
+	padded := false
+	switch f.Kind {
+	case KindInt, KindInt64, KindUint64:
+		ifmt, _ := parseIntFormat(f.Format)
+		if ifmt.base != 10 {
+			return nf, false
+		}
+		padded = ifmt.zeroPadded()
+	case KindFloat:
+		if f.Format == formatSI {
+			return nf, false
+		}
+	default:
+		return nf, false
+	}
+	if opts != nil {
+		nf = opts.Number
+	}
+	if f.Number != nil {
+		nf = *f.Number
+	}
+	if padded {
+		nf.Group = ""
+	}
+	return nf, nf.enabled()
+}
+
+// takesNumberFormat reports whether fields of kind k can have a
+// NumberFormat.
+func takesNumberFormat(k Kind) bool {
+	switch k {
+	case KindInt, KindInt64, KindUint64, KindFloat:
+		return true
+	}
+	return false
+}
+
+// sameNumber reports whether a and b format numbers alike.
+func sameNumber(a, b *NumberFormat) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// numberModifiers returns the spec modifiers that select nf, such as
+// ":de:acct". A format that is not a preset keeps only ":acct".
+func numberModifiers(nf *NumberFormat) string {
+	if nf == nil {
+		return ""
+	}
+	locale := *nf
+	locale.Accounting = false
+	var s string
+	for name, preset := range numberLocales {
+		if preset == locale {
+			s = ":" + name
+		}
+	}
+	if nf.Accounting {
+		s += ":acct"
+	}
+	return s
+}
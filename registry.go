@@ -5,6 +5,7 @@ import (
 	"io"
 	"sort"
 	"strings"
+	"time"
 )
 
 // Registry stores field definitions for type T.
@@ -16,14 +17,34 @@ import (
 // with their own names for organized help output.
 type Registry[T any] struct {
 	name          string
+	namespace     string // qualifier in specs; defaults to name
 	fields        map[string]Field[T]
-	fieldOrder    []string // preserves insertion order
+	fieldOrder    []string          // preserves insertion order
+	duplicates    []string          // names registered again, for Validate
+	inherited     map[string]bool   // names from InheritFieldsFrom, not yet overridden
 	index         map[string]string // lowercase -> canonical name
-	collections   map[string][]string
+	collections   map[string]collection
 	defaults      map[string]string
+	defaultColl   string // collection chosen by SetDefaults
+	views         map[string]view
+	viewOrder     []string
 	subRegistries []*Registry[T]
 }
 
+// collection is a named group of fields. Its members are field names and
+// references to other collections as "@name".
+type collection struct {
+	description string
+	fields      []string
+}
+
+// view is a named view level: the fields of its base view followed by its
+// own spec.
+type view struct {
+	base string
+	spec string
+}
+
 // NewRegistry creates a new unnamed field registry for type T.
 func NewRegistry[T any]() *Registry[T] {
 	return &Registry[T]{
@@ -31,8 +52,9 @@ func NewRegistry[T any]() *Registry[T] {
 		fields:      make(map[string]Field[T]),
 		fieldOrder:  make([]string, 0),
 		index:       make(map[string]string),
-		collections: make(map[string][]string),
+		collections: make(map[string]collection),
 		defaults:    make(map[string]string),
+		views:       make(map[string]view),
 	}
 }
 
@@ -46,6 +68,18 @@ func NewRegistryWithName[T any](name string) *Registry[T] {
 	return reg
 }
 
+// SetNamespace sets the qualifier that selects this registry's fields in a
+// spec when it is a sub-registry, as in "proc.pid". It defaults to the
+// registry name.
+//
+// Qualified names are needed when sub-registries share a field name: an
+// unqualified name that matches fields in several sub-registries is
+// ambiguous and Compile reports an error. Fields of the registry itself
+// take precedence over those of its sub-registries.
+func (r *Registry[T]) SetNamespace(ns string) {
+	r.namespace = ns
+}
+
 // Field starts building a new field definition.
 //
 // Use the returned FieldBuilder to configure the field, then call Register()
@@ -73,27 +107,206 @@ func (r *Registry[T]) Field(name, display, description string) *FieldBuilder[T]
 //
 // The defaultSpec is the comma-separated list of fields used when this
 // collection is referenced with @collection_name. The fields list contains
-// all fields that belong to this collection (for help display).
+// all fields that belong to this collection (for help display). It is
+// shorthand for the Collection builder.
 //
 // Example:
 //
 //	reg.DefineCollection("basic", "name,age", "name", "age", "email", "phone")
 func (r *Registry[T]) DefineCollection(name, defaultSpec string, fields ...string) {
-	r.collections[name] = fields
-	r.defaults[name] = defaultSpec
+	r.Collection(name, "").Fields(fields...).Default(defaultSpec).Register()
+}
+
+// Collection starts building a named collection of fields.
+//
+// Use the returned CollectionBuilder to list the members, which may include
+// other collections, then call Register() to add it to the registry.
+//
+// Example:
+//
+//	reg.Collection("perf", "Performance counters").
+//	    Fields("cpu", "rss", "@io").
+//	    Default("cpu,rss").
+//	    Register()
+func (r *Registry[T]) Collection(name, description string) *CollectionBuilder[T] {
+	return &CollectionBuilder[T]{
+		registry: r,
+		name:     name,
+		coll:     collection{description: description},
+	}
+}
+
+// CollectionBuilder provides a fluent API for collection construction.
+type CollectionBuilder[T any] struct {
+	registry *Registry[T]
+	name     string
+	coll     collection
+	spec     string
 }
 
-// SetDefaults sets the default field specification for a collection.
+// Fields adds members to the collection. A name starting with '@' adds
+// every member of that collection.
+func (b *CollectionBuilder[T]) Fields(names ...string) *CollectionBuilder[T] {
+	b.coll.fields = append(b.coll.fields, names...)
+	return b
+}
+
+// Default sets the spec @name expands to. Without one, @name expands to
+// all members of the collection.
+func (b *CollectionBuilder[T]) Default(spec string) *CollectionBuilder[T] {
+	b.spec = spec
+	return b
+}
+
+// Register adds this collection to the registry, replacing any collection
+// of the same name.
 //
-// When @default is used in a spec, it expands to this value.
+// Members are checked when the collection is used: Compile reports
+// members that are not fields and collections that include themselves.
+func (b *CollectionBuilder[T]) Register() {
+	b.registry.collections[b.name] = b.coll
+	b.registry.defaults[b.name] = b.spec
+}
+
+// memberNames returns the field names of a collection, with referenced
+// collections expanded in place and duplicates dropped. expanding holds
+// the collections being expanded, to detect cycles; a cycle is reported
+// once, with its path.
+func (r *Registry[T]) memberNames(name string, expanding []string) ([]string, error) {
+	for i, outer := range expanding {
+		if outer == name {
+			path := "@" + strings.Join(expanding[i:], " -> @") + " -> @" + name
+			return nil, fmt.Errorf("@%s refers to itself: %s", name, path)
+		}
+	}
+	coll, ok := r.collections[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown collection %q", "@"+name)
+	}
+	expanding = append(expanding, name)
+
+	var names []string
+	for _, m := range coll.fields {
+		members := []string{m}
+		if ref, ok := strings.CutPrefix(m, "@"); ok {
+			if _, ok := r.collections[ref]; !ok {
+				return nil, fmt.Errorf("@%s lists unknown collection %q", name, m)
+			}
+			var err error
+			if members, err = r.memberNames(ref, expanding); err != nil {
+				return nil, err
+			}
+		}
+		for _, m := range members {
+			if !containsString(names, m) {
+				names = append(names, m)
+			}
+		}
+	}
+	return names, nil
+}
+
+// findCollection returns the registry holding the collection or default
+// spec called name, searching sub-registries too, and its name there. A
+// qualified name, ns.name, selects the sub-registry by namespace.
+func (r *Registry[T]) findCollection(name string) (*Registry[T], string, bool) {
+	if _, ok := r.collections[name]; ok {
+		return r, name, true
+	}
+	if _, ok := r.defaults[name]; ok {
+		return r, name, true
+	}
+	for _, sub := range r.subRegistries {
+		if qual := sub.qualifier(); qual != "" {
+			if local, ok := strings.CutPrefix(name, qual+"."); ok {
+				if owner, local, ok := sub.findCollection(local); ok {
+					return owner, local, true
+				}
+			}
+		}
+		if owner, local, ok := sub.findCollection(name); ok {
+			return owner, local, true
+		}
+	}
+	return nil, "", false
+}
+
+// SetDefaults sets the default field specification for a collection and
+// makes that collection the registry default.
+//
+// When @default is used in a spec, it expands to this value, unless an
+// explicit default was set with SetDefault. The last call wins.
 func (r *Registry[T]) SetDefaults(collectionName, spec string) {
 	r.defaults[collectionName] = spec
+	r.defaultColl = collectionName
+}
+
+// SetDefault sets the registry-level default field specification, the one
+// @default expands to. It takes precedence over collection defaults.
+//
+// Without it, @default expands to the collection last passed to
+// SetDefaults, then to a collection named "default", then to the only
+// collection that has a default spec. If several collections qualify,
+// @default is ambiguous and Compile returns an error.
+func (r *Registry[T]) SetDefault(spec string) {
+	r.DefineView("default", "", spec)
+}
+
+// DefineView defines a named view level, referenced in specs as @name.
+//
+// A view shows the fields of its base view, if base is non-empty, followed
+// by the fields in spec, so levels build on each other like the output of
+// ps, ps -f and ps -F:
+//
+//	reg.SetDefault("pid,tty,time,cmd")
+//	reg.DefineView("long", "default", "uid,ppid,stime")
+//	reg.DefineView("wide", "long", "rss,psr")
+//
+// Views take precedence over collections of the same name. Defining the
+// "default" view is the same as calling SetDefault.
+func (r *Registry[T]) DefineView(name, base, spec string) {
+	if _, ok := r.views[name]; !ok {
+		r.viewOrder = append(r.viewOrder, name)
+	}
+	r.views[name] = view{base: base, spec: spec}
+}
+
+// defaultSpec returns the spec @default expands to, following the order
+// documented on SetDefault, and where it came from ("" for an explicit
+// default, otherwise the collection name).
+func (r *Registry[T]) defaultSpec() (spec, from string, err error) {
+	if v, ok := r.views["default"]; ok {
+		return v.spec, "", nil
+	}
+	if r.defaultColl != "" {
+		return r.defaults[r.defaultColl], r.defaultColl, nil
+	}
+	if spec, ok := r.defaults["default"]; ok {
+		return spec, "default", nil
+	}
+
+	var candidates []string
+	for name, spec := range r.defaults {
+		if spec != "" {
+			candidates = append(candidates, name)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return "", "", fmt.Errorf("@default: no default fields defined")
+	case 1:
+		return r.defaults[candidates[0]], candidates[0], nil
+	}
+	sort.Strings(candidates)
+	return "", "", fmt.Errorf("@default is ambiguous: collections @%s all have defaults; use SetDefault or SetDefaults to choose",
+		strings.Join(candidates, ", @"))
 }
 
 // AddRegistry adds a sub-registry to this registry.
 //
 // This enables hierarchical organization of fields. Sub-registries with
-// names will appear as separate sections in help output.
+// names will appear as separate sections in help output, and their fields
+// can be selected by qualified name (see SetNamespace).
 //
 // Example:
 //
@@ -125,7 +338,29 @@ func (r *Registry[T]) AddRegistry(sub *Registry[T]) {
 //	colprint.InheritFieldsFrom(treeReg, procReg, func(n *TreeNode) *Proc { return &n.Proc })
 //	// Now treeReg has all Proc fields, add TreeNode-specific fields
 //	treeReg.Field("cutime", "CUtime", "Cumulative user time").Width(10)...
-func InheritFieldsFrom[T any, S any](dest *Registry[T], source *Registry[S], mapper func(*T) *S) {
+//
+// Options such as WithNamespace and WithCollections configure the
+// destination registry.
+func InheritFieldsFrom[T any, S any](dest *Registry[T], source *Registry[S], mapper func(*T) *S, opts ...InheritOption) {
+	var cfg inheritConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.namespace != "" {
+		dest.namespace = cfg.namespace
+	}
+	if cfg.collections {
+		for name, coll := range source.collections {
+			dest.collections[name] = collection{
+				description: coll.description,
+				fields:      append([]string(nil), coll.fields...),
+			}
+		}
+		for name, spec := range source.defaults {
+			dest.defaults[name] = spec
+		}
+	}
+
 	for _, name := range source.fieldOrder {
 		srcField := source.fields[name]
 		// Create a new field with the same metadata (except Category which is registry-level now)
@@ -133,9 +368,24 @@ func InheritFieldsFrom[T any, S any](dest *Registry[T], source *Registry[S], map
 			Name:        srcField.Name,
 			Display:     srcField.Display,
 			Description: srcField.Description,
+			Aliases:     srcField.Aliases,
+			Deprecated:  srcField.Deprecated,
+			Replacement: srcField.Replacement,
 			Width:       srcField.Width,
+			MinWidth:    srcField.MinWidth,
+			MaxWidth:    srcField.MaxWidth,
+			Priority:    srcField.Priority,
+			Shrinkable:  srcField.Shrinkable,
 			Kind:        srcField.Kind,
 			Precision:   srcField.Precision,
+			Align:       srcField.Align,
+			Truncate:    srcField.Truncate,
+			Wrap:        srcField.Wrap,
+			Format:      srcField.Format,
+			Unit:        srcField.Unit,
+			Location:    srcField.Location,
+			Number:      srcField.Number,
+			RawJSON:     srcField.RawJSON,
 		}
 
 		// Wrap the source field's getter with the mapper
@@ -152,15 +402,69 @@ func InheritFieldsFrom[T any, S any](dest *Registry[T], source *Registry[S], map
 			field.GetFloat = func(t *T) float64 {
 				return srcField.GetFloat(mapper(t))
 			}
+		case KindInt64:
+			field.GetInt64 = func(t *T) int64 {
+				return srcField.GetInt64(mapper(t))
+			}
+		case KindUint64, KindSize:
+			field.GetUint64 = func(t *T) uint64 {
+				return srcField.GetUint64(mapper(t))
+			}
+		case KindTime:
+			field.GetTime = func(t *T) time.Time {
+				return srcField.GetTime(mapper(t))
+			}
+		case KindDuration:
+			field.GetDuration = func(t *T) time.Duration {
+				return srcField.GetDuration(mapper(t))
+			}
 		case KindCustom:
+			if srcField.GetCustomFormat != nil {
+				field.GetCustomFormat = func(buf []byte, t *T, format string) []byte {
+					return srcField.GetCustomFormat(buf, mapper(t), format)
+				}
+				break
+			}
 			field.GetCustom = func(buf []byte, t *T) []byte {
 				return srcField.GetCustom(buf, mapper(t))
 			}
 		}
 
-		dest.fields[name] = field
-		dest.index[strings.ToLower(name)] = name
-		dest.fieldOrder = append(dest.fieldOrder, name)
+		dest.inherit(field)
+	}
+}
+
+// InheritOption configures InheritFieldsFrom.
+type InheritOption func(*inheritConfig)
+
+// inheritConfig holds the settings of InheritOptions.
+type inheritConfig struct {
+	namespace   string
+	collections bool
+}
+
+// WithNamespace sets the namespace of the destination registry, so the
+// inherited fields can be selected as ns.name once it is added as a
+// sub-registry. It is the same as calling SetNamespace.
+//
+// Example:
+//
+//	procFields := colprint.NewRegistryWithName[Thread]("Process Fields")
+//	colprint.InheritFieldsFrom(procFields, procReg, mapper, colprint.WithNamespace("proc"))
+//	reg.AddRegistry(procFields) // "proc.pid" now selects the process ID
+func WithNamespace(ns string) InheritOption {
+	return func(c *inheritConfig) {
+		c.namespace = ns
+	}
+}
+
+// WithCollections copies the source registry's collections and their
+// default specs, so @basic keeps working in the destination registry. If
+// the destination is then added as a sub-registry, its collections are
+// found from the parent too.
+func WithCollections() InheritOption {
+	return func(c *inheritConfig) {
+		c.collections = true
 	}
 }
 
@@ -193,101 +497,338 @@ func (r *Registry[T]) ListCollections() []string {
 	return names
 }
 
+// HelpOptions configures PrintHelpWithOptions.
+type HelpOptions struct {
+	// Collection limits the help to the fields of one collection
+	Collection string
+
+	// ShowDeprecated lists deprecated fields too, marked as such
+	ShowDeprecated bool
+}
+
 // PrintHelp writes formatted help for all fields to w.
 //
 // If collection is non-empty, only fields in that collection are shown.
 // For hierarchical registries, sub-registries are shown as separate sections.
+// Aliases are listed with their field; deprecated fields are hidden.
 func (r *Registry[T]) PrintHelp(w io.Writer, collection string) {
-	var fieldsToShow []string
+	r.PrintHelpWithOptions(w, HelpOptions{Collection: collection})
+}
 
-	if collection != "" {
-		if fields, ok := r.collections[collection]; ok {
-			fieldsToShow = fields
-		} else {
-			fmt.Fprintf(w, "Unknown collection: %s\n", collection)
+// PrintHelpWithOptions writes formatted help for the fields to w, like
+// PrintHelp, with more control over what is shown.
+func (r *Registry[T]) PrintHelpWithOptions(w io.Writer, opts HelpOptions) {
+	if opts.Collection != "" {
+		owner, local, ok := r.findCollection(opts.Collection)
+		var names []string
+		var err error
+		if ok {
+			names, err = owner.memberNames(local, nil)
+		}
+		if !ok || err != nil {
+			fmt.Fprintf(w, "Unknown collection: %s\n", opts.Collection)
 			return
 		}
-	} else {
-		fieldsToShow = r.ListFields(false) // preserve insertion order
+		fields := make([]Field[T], 0, len(names))
+		for _, name := range names {
+			if f, ok := owner.get(name); ok && (!f.Deprecated || opts.ShowDeprecated) {
+				fields = append(fields, f)
+			}
+		}
+		owner.printSection(w, fields)
+		return
 	}
 
-	// Print fields from this registry
-	if len(fieldsToShow) > 0 {
-		sectionName := r.name
-		if sectionName == "" {
-			sectionName = "General"
+	fields := make([]Field[T], 0, len(r.fieldOrder))
+	for _, name := range r.fieldOrder {
+		if f := r.fields[name]; !f.Deprecated || opts.ShowDeprecated {
+			fields = append(fields, f)
 		}
-		fmt.Fprintf(w, "\n%s:\n", sectionName)
+	}
+	r.printSection(w, fields)
 
-		// Collect fields to display
-		fields := make([]Field[T], 0, len(fieldsToShow))
-		for _, name := range fieldsToShow {
-			if f, ok := r.fields[name]; ok {
-				fields = append(fields, f)
+	// Print sub-registries
+	for _, sub := range r.subRegistries {
+		sub.PrintHelpWithOptions(w, opts)
+	}
+
+	if r.name != "" {
+		return
+	}
+
+	// Show the views and collections of the root registry, marking the one
+	// @default expands to
+	_, activeFrom, err := r.defaultSpec()
+	activeDefault := err == nil
+
+	if len(r.views) > 0 {
+		fmt.Fprintf(w, "\nViews:\n")
+		for _, name := range r.viewOrder {
+			v := r.views[name]
+			spec := v.spec
+			if v.base != "" {
+				spec = "@" + v.base
+				if v.spec != "" {
+					spec += "," + v.spec
+				}
 			}
+			fmt.Fprintf(w, "  @%-15s  %s\n", name, spec)
 		}
+	}
 
-		// Calculate column widths
-		maxName := len("Field")
-		maxDisplay := len("Display")
-		for _, f := range fields {
-			if len(f.Name) > maxName {
-				maxName = len(f.Name)
+	if len(r.collections) > 0 {
+		fmt.Fprintf(w, "\nCollections:\n")
+		for _, name := range r.ListCollections() {
+			coll := r.collections[name]
+			// A collection without a default spec expands to its members,
+			// which are listed as registered
+			key, def := "Default", r.defaults[name]
+			if def == "" && len(coll.fields) > 0 {
+				key, def = "Fields", strings.Join(coll.fields, ",")
+			}
+			if def == "" {
+				def = "(no default)"
+			}
+			mark := ""
+			if activeDefault && activeFrom == name {
+				mark = "  (@default)"
 			}
-			if len(f.Display) > maxDisplay {
-				maxDisplay = len(f.Display)
+			label := "@" + name
+			if coll.description != "" {
+				fmt.Fprintf(w, "  %-16s  %s\n", label, coll.description)
+				label = ""
 			}
+			fmt.Fprintf(w, "  %-16s  %s: %s%s\n", label, key, def, mark)
 		}
+	}
+}
 
-		// Print header
-		fmt.Fprintf(w, "  %-*s  %-*s  %s\n", maxName, "Field", maxDisplay, "Display", "Description")
+// printSection writes a help section listing fields under the registry's
+// name.
+func (r *Registry[T]) printSection(w io.Writer, fields []Field[T]) {
+	if len(fields) == 0 {
+		return
+	}
+	sectionName := r.name
+	if sectionName == "" {
+		sectionName = "General"
+	}
+	fmt.Fprintf(w, "\n%s:\n", sectionName)
 
-		// Print fields in order
-		for _, f := range fields {
-			fmt.Fprintf(w, "  %-*s  %-*s  %s\n", maxName, f.Name, maxDisplay, f.Display, f.Description)
+	// Calculate column widths
+	maxName := len("Field")
+	maxDisplay := len("Display")
+	for _, f := range fields {
+		if len(f.Name) > maxName {
+			maxName = len(f.Name)
+		}
+		if len(f.Display) > maxDisplay {
+			maxDisplay = len(f.Display)
 		}
 	}
 
-	// Print sub-registries
+	// Print header
+	fmt.Fprintf(w, "  %-*s  %-*s  %s\n", maxName, "Field", maxDisplay, "Display", "Description")
+
+	// Print fields in order
+	for _, f := range fields {
+		fmt.Fprintf(w, "  %-*s  %-*s  %s\n", maxName, f.Name, maxDisplay, f.Display, helpDescription(f))
+	}
+}
+
+// helpDescription returns a field's description followed by its aliases
+// and, if deprecated, its replacement.
+func helpDescription[T any](f Field[T]) string {
+	desc := f.Description
+	if len(f.Aliases) > 0 {
+		desc += " (alias: " + strings.Join(f.Aliases, ", ") + ")"
+	}
+	if f.Deprecated {
+		if f.Replacement != "" {
+			desc += " (deprecated, use " + f.Replacement + ")"
+		} else {
+			desc += " (deprecated)"
+		}
+	}
+	return strings.TrimSpace(desc)
+}
+
+// allFields returns the fields of this registry followed by those of its
+// sub-registries, depth first, each in insertion order. Deprecated fields
+// are left out. Sub-registry fields whose name is shared with another field
+// are named by their qualified name, such as "proc.pid".
+func (r *Registry[T]) allFields() []Field[T] {
+	entries := r.entries(nil, true)
+	shared := sharedNames(entries)
+	fields := make([]Field[T], 0, len(entries))
+	for _, e := range entries {
+		if !e.field.Deprecated {
+			fields = append(fields, e.qualified(shared))
+		}
+	}
+	return fields
+}
+
+// fieldNames returns the names a field token could have meant: every
+// field, sub-registries included, and every reference as "@name".
+func (r *Registry[T]) fieldNames() []string {
+	var names []string
+	for _, f := range r.allFields() {
+		names = append(names, f.Name)
+	}
+	return append(names, r.refNames()...)
+}
+
+// refNames returns every name that can follow '@', with the '@'.
+func (r *Registry[T]) refNames() []string {
+	names := []string{"@default", "@all"}
+	for _, name := range r.viewOrder {
+		names = append(names, "@"+name)
+	}
+	for _, ref := range r.collectionRefs() {
+		if !containsString(names, ref) {
+			names = append(names, ref)
+		}
+	}
+	return names
+}
+
+// collectionRefs returns "@name" for the collections of this registry,
+// sorted, followed by those of its sub-registries.
+func (r *Registry[T]) collectionRefs() []string {
+	refs := make([]string, 0, len(r.defaults))
+	for name := range r.defaults {
+		refs = append(refs, "@"+name)
+	}
+	sort.Strings(refs)
 	for _, sub := range r.subRegistries {
-		sub.PrintHelp(w, collection)
+		refs = append(refs, sub.collectionRefs()...)
 	}
+	return refs
+}
 
-	// Show collections if no specific collection requested and this is root registry
-	if collection == "" && len(r.collections) > 0 && r.name == "" {
-		fmt.Fprintf(w, "\nCollections:\n")
-		for _, name := range r.ListCollections() {
-			def := r.defaults[name]
-			if def == "" {
-				def = "(no default)"
-			}
-			fmt.Fprintf(w, "  @%-15s  Default: %s\n", name, def)
+// entry is a field together with the qualifier of the registry that holds
+// it.
+type entry[T any] struct {
+	field Field[T]
+	qual  string
+	root  bool
+}
+
+// qualified returns the entry's field, renamed to qual.name if it is in a
+// sub-registry and another field shares its name.
+func (e entry[T]) qualified(shared map[string]int) Field[T] {
+	f := e.field
+	if !e.root && e.qual != "" && shared[strings.ToLower(f.Name)] > 1 {
+		f.Name = e.qual + "." + f.Name
+	}
+	return f
+}
+
+// matches reports whether the entry's field is called name or has it as
+// an alias, ignoring case.
+func (e entry[T]) matches(name string) bool {
+	if strings.EqualFold(e.field.Name, name) {
+		return true
+	}
+	for _, alias := range e.field.Aliases {
+		if strings.EqualFold(alias, name) {
+			return true
 		}
 	}
+	return false
 }
 
-// get retrieves a field by name (case-insensitive).
-// Searches this registry and all sub-registries.
+// qualifier returns the name that qualifies this registry's fields in a
+// spec: its namespace, or else its name.
+func (r *Registry[T]) qualifier() string {
+	if r.namespace != "" {
+		return r.namespace
+	}
+	return r.name
+}
+
+// entries appends the fields of this registry and its sub-registries to
+// dst, depth first, each in insertion order.
+func (r *Registry[T]) entries(dst []entry[T], root bool) []entry[T] {
+	qual := r.qualifier()
+	for _, name := range r.fieldOrder {
+		dst = append(dst, entry[T]{field: r.fields[name], qual: qual, root: root})
+	}
+	for _, sub := range r.subRegistries {
+		dst = sub.entries(dst, false)
+	}
+	return dst
+}
+
+// sharedNames counts the fields of each lowercased name.
+func sharedNames[T any](entries []entry[T]) map[string]int {
+	shared := make(map[string]int, len(entries))
+	for _, e := range entries {
+		shared[strings.ToLower(e.field.Name)]++
+	}
+	return shared
+}
+
+// get retrieves a field by name (case-insensitive), like lookup, and
+// reports whether exactly one field matched.
 func (r *Registry[T]) get(name string) (Field[T], bool) {
+	f, _, ok := r.lookup(name)
+	return f, ok
+}
+
+// lookup retrieves a field by name or alias, case-insensitively.
+//
+// Fields of this registry come first. A qualified name, ns.name, selects
+// the field from the sub-registry with that namespace or name. Otherwise
+// the name must match a single field among the sub-registries; if it
+// matches several, lookup fails and returns their qualified names.
+func (r *Registry[T]) lookup(name string) (Field[T], []string, bool) {
 	// Try exact match first
 	if f, ok := r.fields[name]; ok {
-		return f, true
+		return f, nil, true
 	}
 
 	// Try case-insensitive
 	if canonical, ok := r.index[strings.ToLower(name)]; ok {
-		return r.fields[canonical], true
+		return r.fields[canonical], nil, true
+	}
+
+	entries := r.entries(nil, true)
+	shared := sharedNames(entries)
+
+	// Qualified names; the namespace itself may contain dots
+	for i := 0; i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+		qual, rest := name[:i], name[i+1:]
+		for _, e := range entries {
+			if e.qual != "" && strings.EqualFold(e.qual, qual) && e.matches(rest) {
+				return e.qualified(shared), nil, true
+			}
+		}
 	}
 
 	// Search sub-registries
-	for _, sub := range r.subRegistries {
-		if f, ok := sub.get(name); ok {
-			return f, ok
+	var found []entry[T]
+	for _, e := range entries {
+		if !e.root && e.matches(name) {
+			found = append(found, e)
 		}
 	}
+	if len(found) == 1 {
+		return found[0].qualified(shared), nil, true
+	}
 
 	var zero Field[T]
-	return zero, false
+	var candidates []string
+	for _, e := range found {
+		if e.qual != "" {
+			candidates = append(candidates, e.qual+"."+e.field.Name)
+		}
+	}
+	return zero, candidates, false
 }
 
 // FieldBuilder provides a fluent API for field construction.
@@ -302,6 +843,64 @@ func (b *FieldBuilder[T]) Width(w int) *FieldBuilder[T] {
 	return b
 }
 
+// MinWidth sets the narrowest width automatic sizing may choose.
+func (b *FieldBuilder[T]) MinWidth(w int) *FieldBuilder[T] {
+	b.field.MinWidth = w
+	return b
+}
+
+// MaxWidth sets the widest width automatic sizing may choose.
+func (b *FieldBuilder[T]) MaxWidth(w int) *FieldBuilder[T] {
+	b.field.MaxWidth = w
+	return b
+}
+
+// Priority sets how important the column is when the line is too narrow.
+// Columns with the lowest priority are dropped first.
+func (b *FieldBuilder[T]) Priority(p int) *FieldBuilder[T] {
+	b.field.Priority = p
+	return b
+}
+
+// Shrinkable lets the column be narrowed to fit a narrow line.
+func (b *FieldBuilder[T]) Shrinkable() *FieldBuilder[T] {
+	b.field.Shrinkable = true
+	return b
+}
+
+// Align sets how values are positioned within the column.
+//
+// Use AlignRight for numbers, AlignDecimal to line floats up on the
+// decimal point, or AlignCenter for short flags.
+func (b *FieldBuilder[T]) Align(a Align) *FieldBuilder[T] {
+	b.field.Align = a
+	return b
+}
+
+// Truncate sets how values wider than the column are shortened.
+func (b *FieldBuilder[T]) Truncate(t Truncate) *FieldBuilder[T] {
+	b.field.Truncate = t
+	return b
+}
+
+// Wrap word-wraps values wider than the column onto continuation lines.
+//
+// A row with a wrapped value spans several lines; the other columns are
+// left blank on the extra lines. Words longer than the column are broken.
+func (b *FieldBuilder[T]) Wrap() *FieldBuilder[T] {
+	b.field.Wrap = true
+	return b
+}
+
+// Precision sets the number of fraction digits.
+//
+// Float sets this too; for other kinds it is the number of digits
+// AlignDecimal reserves right of the decimal point.
+func (b *FieldBuilder[T]) Precision(p int) *FieldBuilder[T] {
+	b.field.Precision = p
+	return b
+}
+
 // String configures this field as a string type.
 //
 // The provided function extracts the string value from the object.
@@ -320,9 +919,66 @@ func (b *FieldBuilder[T]) Int(fn func(*T) int) *FieldBuilder[T] {
 	return b
 }
 
+// Int64 configures this field as a 64-bit integer type.
+//
+// The provided function extracts the int64 value from the object.
+func (b *FieldBuilder[T]) Int64(fn func(*T) int64) *FieldBuilder[T] {
+	b.field.Kind = KindInt64
+	b.field.GetInt64 = fn
+	return b
+}
+
+// Uint64 configures this field as an unsigned 64-bit integer type, for
+// counters such as byte counts and inode numbers.
+//
+// The provided function extracts the uint64 value from the object.
+func (b *FieldBuilder[T]) Uint64(fn func(*T) uint64) *FieldBuilder[T] {
+	b.field.Kind = KindUint64
+	b.field.GetUint64 = fn
+	return b
+}
+
+// Size configures this field as a byte count, shown with a unit such as
+// "1.5Gi", rounded to three significant digits (see Precision).
+//
+// The format selects the style, which a spec can change as well:
+//
+//	iec       1.5Gi    powers of 1024 (the default)
+//	binary    1.5G     powers of 1024, like ls -h
+//	si        1.5G     powers of 1000
+//	iec-long  1.5 GiB
+//	si-long   1.5 GB
+//
+// Add '@' and a unit to always use that unit, as in "binary@M" for
+// "1536M"; "@B" shows plain bytes.
+func (b *FieldBuilder[T]) Size(format string, fn func(*T) uint64) *FieldBuilder[T] {
+	b.field.Kind = KindSize
+	b.field.Format = format
+	b.field.Precision = 3
+	b.field.GetUint64 = fn
+	return b
+}
+
+// Scaled configures this field as a floating-point type shown with an SI
+// prefix and unit, as in "1.5GHz", "12µs" or "3.2k ops/s", rounded to
+// three significant digits (see Precision). The unit is appended as
+// given, so include a leading space if wanted.
+//
+// Any Float field can be scaled with the "%si" format, as in "lat:%si".
+func (b *FieldBuilder[T]) Scaled(unit string, fn func(*T) float64) *FieldBuilder[T] {
+	b.field.Kind = KindFloat
+	b.field.Format = formatSI
+	b.field.Unit = unit
+	b.field.Precision = 3
+	b.field.GetFloat = fn
+	return b
+}
+
 // Float configures this field as a floating-point type.
 //
 // The precision parameter specifies the number of decimal places (e.g., 2 for "3.14").
+// Format selects another notation: "%e", "%eng" (engineering), "%g"
+// (shortest), "%sig" (precision counts significant digits) or "%si".
 func (b *FieldBuilder[T]) Float(precision int, fn func(*T) float64) *FieldBuilder[T] {
 	b.field.Kind = KindFloat
 	b.field.Precision = precision
@@ -346,14 +1002,173 @@ func (b *FieldBuilder[T]) Custom(fn func(dst []byte, v *T) []byte) *FieldBuilder
 	return b
 }
 
+// CustomFormat configures this field with a custom formatter that takes a
+// format hint: the field's Format, as set with the Format method or a
+// "%..." modifier in the spec.
+//
+// Example:
+//
+//	CustomFormat(func(dst []byte, e *Event, format string) []byte {
+//	    return appendStrftime(dst, e.Start, format)
+//	}).Format("%H:%M")
+func (b *FieldBuilder[T]) CustomFormat(fn func(dst []byte, v *T, format string) []byte) *FieldBuilder[T] {
+	b.field.Kind = KindCustom
+	b.field.GetCustomFormat = fn
+	return b
+}
+
+// Time configures this field as a time.Time type, written with layout: a
+// time.Format layout such as time.Kitchen or "15:04", a name such as
+// "rfc3339", "datetime", "date" or "time", LayoutRelative for "3m ago",
+// or LayoutUnix. An empty layout means "datetime". The zero time is
+// written as an empty cell.
+//
+// The layout is the field's Format, so a spec can change it, as in
+// "start:%relative" or "start:%15:04". Use In, or the "utc" and "local"
+// modifiers, to pick the time zone.
+func (b *FieldBuilder[T]) Time(layout string, fn func(*T) time.Time) *FieldBuilder[T] {
+	b.field.Kind = KindTime
+	b.field.Format = layout
+	b.field.GetTime = fn
+	return b
+}
+
+// In shows the values of a Time field in the time zone loc, such as
+// time.UTC or time.Local, instead of their own.
+func (b *FieldBuilder[T]) In(loc *time.Location) *FieldBuilder[T] {
+	b.field.Location = loc
+	return b
+}
+
+// Number groups the digits of an Int or Float field and sets its decimal
+// separator, overriding Options.Number. Use a preset such as NumberEN or
+// NumberIN, or NumberFormat{} to write the field as plain digits.
+func (b *FieldBuilder[T]) Number(nf NumberFormat) *FieldBuilder[T] {
+	b.field.Number = &nf
+	return b
+}
+
+// Duration configures this field as a time.Duration type, written in the
+// given style. A spec can change the style with "%compact", "%seconds" or
+// "%clock", and the fraction digits of the last two with a precision.
+func (b *FieldBuilder[T]) Duration(style DurationStyle, fn func(*T) time.Duration) *FieldBuilder[T] {
+	b.field.Kind = KindDuration
+	b.field.Format = style.String()
+	b.field.Precision = -1
+	b.field.GetDuration = fn
+	return b
+}
+
+// Format sets the default format hint. Integer fields take "%d" (the
+// default), "%x", "%X", "%o" or "%b", with '#' for a 0x, 0b or 0 prefix
+// and a zero-padded digit count, as in "%#08x" or "%04o". Float fields
+// take "%f" (the default), "%e", "%eng", "%g", "%sig" or "%si". Size
+// fields take a style and optional unit, as in "iec", "si-long" or
+// "binary@M". Time fields take strftime verbs ("%Y-%m-%d"), a Go layout
+// ("%15:04") or a layout name ("%date", "%relative", "%unix"). Duration
+// fields take "%compact", "%seconds" or "%clock". CustomFormat fields
+// interpret it themselves.
+func (b *FieldBuilder[T]) Format(format string) *FieldBuilder[T] {
+	b.field.Format = format
+	return b
+}
+
+// Alias adds other names the field can be selected by in a spec, such as
+// a field's name before it was renamed. Aliases are shown in help.
+func (b *FieldBuilder[T]) Alias(names ...string) *FieldBuilder[T] {
+	b.field.Aliases = append(b.field.Aliases, names...)
+	return b
+}
+
+// Deprecated marks the field as deprecated. It is hidden from help, @all
+// and patterns, and Compile reports a warning when it is used (see
+// Program.Warnings).
+//
+// If replacement names another field, specs are migrated automatically:
+// the replacement is used instead, with any modifiers given. A deprecated
+// field with a replacement needs no getter.
+//
+// Example:
+//
+//	reg.Field("rss_kb", "RSS", "Resident set size").
+//	    Deprecated("rss").
+//	    Register()
+func (b *FieldBuilder[T]) Deprecated(replacement string) *FieldBuilder[T] {
+	b.field.Deprecated = true
+	b.field.Replacement = replacement
+	return b
+}
+
+// RawJSON marks a Custom field's output as JSON to embed as-is in JSON
+// output, rather than quoting it as a string. An empty value becomes null.
+func (b *FieldBuilder[T]) RawJSON() *FieldBuilder[T] {
+	b.field.RawJSON = true
+	return b
+}
+
 // Register adds this field to the registry.
 //
-// This is the final step in the builder chain.
+// This is the final step in the builder chain. Register does not check
+// the field; if the name is taken, the field replaces the earlier one.
+// That is how a field copied by InheritFieldsFrom is overridden; any other
+// name registered twice, like other mistakes, is reported by
+// Registry.Validate, and MustRegister panics on it.
 func (b *FieldBuilder[T]) Register() {
-	name := b.field.Name
-	b.registry.fields[name] = b.field
-	b.registry.index[strings.ToLower(name)] = name
+	b.registry.add(b.field)
+}
 
-	// Track insertion order
-	b.registry.fieldOrder = append(b.registry.fieldOrder, name)
+// MustRegister adds this field to the registry like Register, but panics
+// if the field is invalid (see Registry.Validate) or its name is taken.
+// It suits registries built in init functions or package variables.
+func (b *FieldBuilder[T]) MustRegister() {
+	problems := checkField(b.field)
+	if _, ok := b.registry.fields[b.field.Name]; ok && !b.registry.inherited[b.field.Name] {
+		problems = append(problems, "registered more than once")
+	}
+	if len(problems) > 0 {
+		panic(fmt.Sprintf("colprint: field %q: %s", b.field.Name, strings.Join(problems, "; ")))
+	}
+	b.Register()
+}
+
+// add stores a field and indexes its name and aliases. A field whose name
+// is already taken replaces the earlier one in its place. The name is
+// recorded for Validate unless the earlier field was inherited, which
+// makes the replacement an override.
+func (r *Registry[T]) add(f Field[T]) {
+	name := f.Name
+	if old, ok := r.fields[name]; ok {
+		if r.inherited[name] {
+			delete(r.inherited, name)
+		} else {
+			r.duplicates = append(r.duplicates, name)
+		}
+		for _, alias := range old.Aliases {
+			key := strings.ToLower(alias)
+			if r.index[key] == name && key != strings.ToLower(name) {
+				delete(r.index, key)
+			}
+		}
+	} else {
+		r.fieldOrder = append(r.fieldOrder, name)
+	}
+	r.fields[name] = f
+	r.index[strings.ToLower(name)] = name
+	for _, alias := range f.Aliases {
+		// Names win over aliases, and the first alias over later ones
+		key := strings.ToLower(alias)
+		if _, ok := r.index[key]; !ok {
+			r.index[key] = name
+		}
+	}
+}
+
+// inherit adds a field copied by InheritFieldsFrom, which a later Register
+// may override.
+func (r *Registry[T]) inherit(f Field[T]) {
+	r.add(f)
+	if r.inherited == nil {
+		r.inherited = make(map[string]bool)
+	}
+	r.inherited[f.Name] = true
 }
@@ -0,0 +1,181 @@
+package colprint
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// sizeFormat is the formatting of a Size field, parsed from its format
+// hint.
+type sizeFormat struct {
+	base float64 // 1024 or 1000
+	iec  bool    // "Ki" rather than "K"
+	long bool    // a space and a "B", as in "1.5 GB"
+	unit int     // fixed power of base, or -1 to choose per value
+}
+
+// sizeStyles maps the style names a Size field's Format may use.
+var sizeStyles = map[string]sizeFormat{
+	"iec":      {base: 1024, iec: true},
+	"binary":   {base: 1024},
+	"si":       {base: 1000},
+	"iec-long": {base: 1024, iec: true, long: true},
+	"si-long":  {base: 1000, long: true},
+}
+
+// sizeUnits are the unit letters for powers 1 to 6 of the base.
+const sizeUnits = "KMGTPE"
+
+// parseSizeFormat parses a Size format hint: a style name from sizeStyles,
+// "iec" by default, optionally followed by '@' and a fixed unit such as
+// "M", "Mi" or "MB" ("B" for bytes), with or without a leading '%'.
+func parseSizeFormat(format string) (sizeFormat, bool) {
+	format = strings.TrimPrefix(format, "%")
+	style, unit, fixed := strings.Cut(format, "@")
+	if fixed && unit == "" {
+		return sizeFormat{}, false
+	}
+	if style == "" {
+		style = "iec"
+	}
+	f, ok := sizeStyles[strings.ToLower(style)]
+	if !ok {
+		return sizeFormat{}, false
+	}
+	f.unit = -1
+	if fixed {
+		u := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(unit), "B"), "I")
+		switch i := strings.Index(sizeUnits, u); {
+		case u == "":
+			f.unit = 0
+		case len(u) == 1 && i >= 0:
+			f.unit = i + 1
+		default:
+			return sizeFormat{}, false
+		}
+	}
+	return f, true
+}
+
+// appendSize appends the byte count n, scaled to a unit and rounded to
+// digits significant digits.
+func (f sizeFormat) appendSize(dst []byte, n uint64, digits int) []byte {
+	exp := f.unit
+	if exp < 0 {
+		exp = 0
+		for v := float64(n); v >= f.base && exp < len(sizeUnits); v /= f.base {
+			exp++
+		}
+	}
+
+	if exp == 0 {
+		dst = strconv.AppendUint(dst, n, 10)
+	} else {
+		v := float64(n) / math.Pow(f.base, float64(exp))
+		// Rounding can carry into the next unit, as 1023.9K does
+		if f.unit < 0 && exp < len(sizeUnits) && roundSignificant(v, digits) >= f.base {
+			exp++
+			v /= f.base
+		}
+		dst = appendSignificant(dst, v, digits)
+	}
+
+	if f.long {
+		dst = append(dst, ' ')
+	}
+	if exp > 0 {
+		u := sizeUnits[exp-1]
+		if u == 'K' && f.base == 1000 {
+			u = 'k'
+		}
+		dst = append(dst, u)
+		if f.iec {
+			dst = append(dst, 'i')
+		}
+	}
+	if f.long || (f.iec && exp == 0) {
+		dst = append(dst, 'B')
+	}
+	return dst
+}
+
+// siPrefixes are the SI prefixes from pico (10^-12) to exa (10^18).
+var siPrefixes = [...]string{"p", "n", "µ", "m", "", "k", "M", "G", "T", "P", "E"}
+
+// siZero is the index of the empty prefix in siPrefixes.
+const siZero = 4
+
+// appendSI appends v scaled by an SI prefix so that between 1 and 999
+// remain, rounded to digits significant digits, followed by the prefix
+// and unit, as in "1.5GHz" or "12µs". Values beyond the prefixes, from
+// pico to exa, are written in 'e' notation instead, as in "1.79e+308Hz".
+func appendSI(dst []byte, v float64, digits int, unit string) []byte {
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		dst = strconv.AppendFloat(dst, v, 'g', -1, 64)
+		return append(dst, unit...)
+	}
+	if v < 0 {
+		dst = append(dst, '-')
+		v = -v
+	}
+	abs := v
+	exp := siZero
+	for v >= 1000 && exp < len(siPrefixes)-1 {
+		v /= 1000
+		exp++
+	}
+	for v < 1 && exp > 0 {
+		v *= 1000
+		exp--
+	}
+	if exp < len(siPrefixes)-1 && roundSignificant(v, digits) >= 1000 {
+		v /= 1000
+		exp++
+	}
+	if r := roundSignificant(v, digits); r < 1 || r >= 1000 {
+		if digits <= 0 {
+			digits = 3
+		}
+		dst = strconv.AppendFloat(dst, abs, 'e', digits-1, 64)
+		return append(dst, unit...)
+	}
+	dst = appendSignificant(dst, v, digits)
+	dst = append(dst, siPrefixes[exp]...)
+	return append(dst, unit...)
+}
+
+// significantDecimals returns the number of fraction digits that show
+// v >= 0 with digits significant digits (3 if digits is not positive).
+func significantDecimals(v float64, digits int) int {
+	if digits <= 0 {
+		digits = 3
+	}
+	n := 1
+	for ; v >= 10; v /= 10 {
+		n++
+	}
+	return max(digits-n, 0)
+}
+
+// roundSignificant rounds v >= 0 the way appendSignificant writes it.
+func roundSignificant(v float64, digits int) float64 {
+	scale := math.Pow(10, float64(significantDecimals(v, digits)))
+	return math.Round(v*scale) / scale
+}
+
+// appendSignificant appends v >= 0 with digits significant digits, or all
+// its integer digits if it has more, without trailing fraction zeros.
+func appendSignificant(dst []byte, v float64, digits int) []byte {
+	dec := significantDecimals(v, digits)
+	dst = strconv.AppendFloat(dst, v, 'f', dec, 64)
+	if dec > 0 {
+		for dst[len(dst)-1] == '0' {
+			dst = dst[:len(dst)-1]
+		}
+		if dst[len(dst)-1] == '.' {
+			dst = dst[:len(dst)-1]
+		}
+	}
+	return dst
+}
@@ -0,0 +1,348 @@
+package colprint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spec is a parsed field specification, as returned by ParseSpec.
+//
+// It records what was written, not what it expands to: references are
+// not resolved and names are not checked against a registry.
+type Spec struct {
+	// Source is the specification as given
+	Source string
+
+	// Items are the tokens of the spec, in order; empty tokens are skipped
+	Items []SpecItem
+}
+
+// ItemKind tells what a SpecItem refers to.
+type ItemKind int
+
+const (
+	// ItemField is a field name, such as "pid"
+	ItemField ItemKind = iota
+	// ItemPattern is a glob pattern, such as "io_*"
+	ItemPattern
+	// ItemRef is a reference to a view or collection, such as "@default"
+	ItemRef
+	// ItemDefine defines a collection inline, such as "@mine=pid,cmd;"
+	ItemDefine
+)
+
+// SpecItem is a single comma-separated token of a spec, or an inline
+// definition.
+type SpecItem struct {
+	Kind ItemKind
+
+	// Remove is set for "-" tokens, which remove fields rather than add them
+	Remove bool
+
+	// Name is the field name, the pattern, or the reference or definition
+	// without '@'
+	Name string
+
+	// Header is the header set by "name=Header", or "" if not renamed
+	Header string
+
+	// Modifiers are the colon-separated modifiers after the name
+	Modifiers []SpecModifier
+
+	// Items are the tokens of an ItemDefine
+	Items []SpecItem
+
+	// Pos and End are the byte offsets of the token in Source, without
+	// surrounding spaces; NamePos is the offset of Name
+	Pos, End, NamePos int
+
+	cs colSpec // the overrides the modifiers set
+}
+
+// SpecModifier is a single modifier of a SpecItem, such as ">20", "wrap"
+// or "%Y-%m-%d".
+type SpecModifier struct {
+	// Text is the modifier as written, without surrounding spaces
+	Text string
+
+	// Pos is the byte offset of Text in the spec
+	Pos int
+}
+
+// ParseSpec parses a field specification into its tokens without
+// resolving it against a registry. It checks the syntax only: unknown
+// fields and collections are reported by Compile.
+//
+// A token "@name=..." defines a collection inline. Its fields run to the
+// next ';' or the end of the spec, so "@mine=pid,cmd;@mine,rss" is a
+// definition followed by two tokens. Definitions cannot be nested.
+//
+// All malformed tokens are reported at once, as SpecErrors; the returned
+// Spec then holds the well-formed items.
+//
+// See Compile for the spec grammar.
+func ParseSpec(spec string) (*Spec, error) {
+	s := &Spec{Source: spec}
+	var errs SpecErrors
+	s.Items = parseItems(spec, 0, len(spec), true, &errs)
+	if len(errs) > 0 {
+		for _, e := range errs {
+			e.Spec = spec
+		}
+		return s, errs
+	}
+	return s, nil
+}
+
+// parseItems parses the tokens of spec[start:end], separated by ',' or
+// ';', appending any errors to errs. Definitions are allowed at the top
+// level only.
+func parseItems(spec string, start, end int, top bool, errs *SpecErrors) []SpecItem {
+	var items []SpecItem
+	for start <= end {
+		stop := strings.IndexAny(spec[start:end], ",;")
+		if stop < 0 {
+			stop = end
+		} else {
+			stop += start
+		}
+		pos, tok := trimSpan(spec[start:stop], start)
+		start = stop + 1
+		if tok == "" {
+			continue
+		}
+
+		if eq := strings.IndexByte(tok, '='); strings.HasPrefix(tok, "@") && eq > 0 {
+			// The definition runs to the next ';'
+			bodyStart := pos + eq + 1
+			bodyEnd := strings.IndexByte(spec[bodyStart:end], ';')
+			if bodyEnd < 0 {
+				bodyEnd = end
+			} else {
+				bodyEnd += bodyStart
+			}
+			start = bodyEnd + 1
+
+			item, err := parseDefinition(spec, pos, bodyStart, bodyEnd, top, errs)
+			if err != nil {
+				*errs = append(*errs, err)
+				continue
+			}
+			items = append(items, item)
+			continue
+		}
+
+		item, err := parseItem(tok, pos)
+		if err != nil {
+			*errs = append(*errs, err)
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// parseDefinition parses the definition at offset pos of spec, whose
+// fields are spec[bodyStart:bodyEnd].
+func parseDefinition(spec string, pos, bodyStart, bodyEnd int, top bool, errs *SpecErrors) (SpecItem, *SpecError) {
+	whole := strings.TrimRight(spec[pos:bodyEnd], " \t")
+	item := SpecItem{Kind: ItemDefine, Pos: pos, End: pos + len(whole)}
+	item.NamePos, item.Name = trimSpan(spec[pos+1:bodyStart-1], pos+1)
+	switch {
+	case !top:
+		return item, &SpecError{Pos: pos, Token: whole, Msg: "definitions cannot be nested"}
+	case item.Name == "":
+		return item, &SpecError{Pos: pos, Token: whole, Msg: "missing name after '@'"}
+	case !validRefName(item.Name):
+		return item, &SpecError{Pos: item.NamePos, Token: item.Name,
+			Msg: fmt.Sprintf("invalid collection name %q", item.Name)}
+	}
+
+	n := len(*errs)
+	item.Items = parseItems(spec, bodyStart, bodyEnd, false, errs)
+	if len(item.Items) == 0 && len(*errs) == n {
+		return item, &SpecError{Pos: pos, Token: whole,
+			Msg: fmt.Sprintf("definition of %q lists no fields", "@"+item.Name)}
+	}
+	return item, nil
+}
+
+// validRefName reports whether "@name" parses as a reference to name.
+func validRefName(name string) bool {
+	if strings.ContainsAny(name, ",;=") {
+		return false
+	}
+	item, err := parseItem("@"+name, 0)
+	return err == nil && item.Name == name
+}
+
+// parseItem parses the token tok found at offset pos of a spec.
+func parseItem(tok string, pos int) (SpecItem, *SpecError) {
+	item := SpecItem{Pos: pos, End: pos + len(tok), NamePos: pos}
+	whole := tok
+	if strings.HasPrefix(tok, "-") {
+		item.Remove = true
+		item.NamePos, tok = trimSpan(tok[1:], pos+1)
+		if tok == "" {
+			return item, &SpecError{Pos: pos, Token: whole, Msg: "missing field name after '-'"}
+		}
+		if i := strings.IndexAny(tok, ":="); i >= 0 {
+			return item, &SpecError{Pos: item.NamePos + i, Token: tok[i:],
+				Msg: fmt.Sprintf("modifiers are not allowed on removal %q", whole)}
+		}
+	}
+
+	if strings.HasPrefix(tok, "@") {
+		item.Kind = ItemRef
+		item.NamePos, item.Name = trimSpan(tok[1:], item.NamePos+1)
+		if item.Name == "" {
+			return item, &SpecError{Pos: pos, Token: whole, Msg: "missing name after '@'"}
+		}
+		if i := strings.IndexAny(item.Name, ":="); i >= 0 {
+			return item, &SpecError{Pos: item.NamePos + i, Token: item.Name[i:],
+				Msg: fmt.Sprintf("modifiers are not allowed on %q", tok)}
+		}
+		return item, nil
+	}
+
+	cs, mods, err := parseToken(tok)
+	if err != nil {
+		se := err.(*SpecError)
+		se.Pos += item.NamePos
+		return item, se
+	}
+	item.Name, item.cs = cs.name, cs
+	if cs.hasDisplay {
+		item.Header = cs.display
+	}
+	for i := range mods {
+		mods[i].Pos += item.NamePos
+	}
+	item.Modifiers = mods
+	if isGlob(cs.name) {
+		item.Kind = ItemPattern
+	}
+	return item, nil
+}
+
+// trimSpan trims spaces around s, which starts at offset pos, returning the
+// new offset and the trimmed text.
+func trimSpan(s string, pos int) (int, string) {
+	trimmed := strings.TrimLeft(s, " \t")
+	pos += len(s) - len(trimmed)
+	return pos, strings.TrimRight(trimmed, " \t")
+}
+
+// String returns the item as it would be written in a spec.
+func (it SpecItem) String() string {
+	var b strings.Builder
+	if it.Remove {
+		b.WriteByte('-')
+	}
+	if it.Kind == ItemRef || it.Kind == ItemDefine {
+		b.WriteByte('@')
+	}
+	b.WriteString(it.Name)
+	if it.Kind == ItemDefine {
+		b.WriteByte('=')
+		for i, sub := range it.Items {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(sub.String())
+		}
+		b.WriteByte(';')
+		return b.String()
+	}
+	if it.Header != "" {
+		b.WriteByte('=')
+		b.WriteString(it.Header)
+	}
+	for _, m := range it.Modifiers {
+		b.WriteByte(':')
+		b.WriteString(m.Text)
+	}
+	return b.String()
+}
+
+// String returns the spec with spacing normalized and empty tokens
+// removed.
+func (s *Spec) String() string {
+	var b strings.Builder
+	for i, it := range s.Items {
+		if i > 0 && s.Items[i-1].Kind != ItemDefine {
+			b.WriteByte(',')
+		}
+		b.WriteString(it.String())
+	}
+	return b.String()
+}
+
+// canonicalSpec returns the spec that selects fields exactly as given:
+// every field by name, in order, with the modifiers that set whatever
+// differs from the field as registered.
+func canonicalSpec[T any](reg *Registry[T], fields []Field[T]) string {
+	var b strings.Builder
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(f.Name)
+		base, _ := reg.get(f.Name)
+		if f.Display != base.Display {
+			b.WriteByte('=')
+			b.WriteString(f.Display)
+		}
+
+		// [align][width][.precision]
+		var layout []byte
+		if f.Align != base.Align {
+			for c, a := range alignPrefixes {
+				if a == f.Align {
+					layout = append(layout, c)
+				}
+			}
+		}
+		if f.Width != base.Width || f.MinWidth != base.MinWidth || f.MaxWidth != base.MaxWidth {
+			layout = strconv.AppendInt(layout, int64(f.Width), 10)
+		}
+		if f.Precision != base.Precision {
+			layout = append(layout, '.')
+			layout = strconv.AppendInt(layout, int64(f.Precision), 10)
+		}
+		if len(layout) > 0 {
+			b.WriteByte(':')
+			b.Write(layout)
+		}
+
+		if f.Truncate != base.Truncate {
+			for name, t := range truncateNames {
+				if t == f.Truncate {
+					b.WriteByte(':')
+					b.WriteString(name)
+				}
+			}
+		}
+		if f.Wrap && !base.Wrap {
+			b.WriteString(":wrap")
+		}
+		if f.Location != base.Location {
+			switch f.Location {
+			case time.UTC:
+				b.WriteString(":utc")
+			case time.Local:
+				b.WriteString(":local")
+			}
+		}
+		if !sameNumber(f.Number, base.Number) {
+			b.WriteString(numberModifiers(f.Number))
+		}
+		if f.Format != base.Format && f.Format != "" {
+			b.WriteByte(':')
+			b.WriteString(f.Format)
+		}
+	}
+	return b.String()
+}
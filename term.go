@@ -0,0 +1,18 @@
+package colprint
+
+import (
+	"os"
+	"strconv"
+)
+
+// terminalWidth returns the width of the terminal on stdout, falling back
+// to the COLUMNS environment variable. It returns 0 when neither is known.
+func terminalWidth() int {
+	if w, ok := ttyWidth(os.Stdout.Fd()); ok {
+		return w
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package colprint
+
+// ttyWidth reports no terminal width on platforms without TIOCGWINSZ;
+// terminalWidth falls back to $COLUMNS.
+func ttyWidth(fd uintptr) (int, bool) {
+	return 0, false
+}
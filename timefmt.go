@@ -0,0 +1,316 @@
+package colprint
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DurationStyle selects how Duration fields are written.
+type DurationStyle int
+
+const (
+	// DurationCompact writes the two most significant units, as in
+	// "1h02m", "3m05s", "2d04h", or a single unit below a minute, as in
+	// "45s" or "850ms". This is the default.
+	DurationCompact DurationStyle = iota
+	// DurationSeconds writes seconds, as in "62.5s", with Precision
+	// fraction digits, or as few as needed when Precision is negative.
+	DurationSeconds
+	// DurationClock writes hours, minutes and seconds, as in "01:02:03",
+	// with Precision fraction digits of the seconds. Hours go past 24.
+	DurationClock
+)
+
+// durationStyles maps the names of the duration styles, as used in Format.
+var durationStyles = map[string]DurationStyle{
+	"compact": DurationCompact,
+	"seconds": DurationSeconds,
+	"clock":   DurationClock,
+}
+
+// String returns the style's name: "compact", "seconds" or "clock".
+func (s DurationStyle) String() string {
+	for name, style := range durationStyles {
+		if style == s {
+			return name
+		}
+	}
+	return "DurationStyle(" + strconv.Itoa(int(s)) + ")"
+}
+
+// durationStyle returns the style a Duration field's Format names, with or
+// without a leading '%'.
+func durationStyle(format string) (DurationStyle, bool) {
+	format = strings.TrimPrefix(format, "%")
+	if format == "" {
+		return DurationCompact, true
+	}
+	style, ok := durationStyles[strings.ToLower(format)]
+	return style, ok
+}
+
+// Layouts for Time fields that are not time.Format layouts.
+const (
+	// LayoutRelative writes how long ago a time was, as in "3m ago", or
+	// how far off it is, as in "in 2h"
+	LayoutRelative = "relative"
+	// LayoutUnix writes a time as seconds since the Unix epoch
+	LayoutUnix = "unix"
+)
+
+// timeLayouts maps the layout names a Time field's Format may use.
+var timeLayouts = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"rfc1123":     time.RFC1123,
+	"datetime":    time.DateTime,
+	"date":        time.DateOnly,
+	"time":        time.TimeOnly,
+	"kitchen":     time.Kitchen,
+	"stamp":       time.Stamp,
+}
+
+// timeLayout returns the layout a Time field's Format selects: a strftime
+// format, a name from timeLayouts, LayoutRelative, LayoutUnix, or else a
+// time.Format layout, with or without a leading '%'. The default is
+// time.DateTime.
+func timeLayout(format string) string {
+	if isStrftime(format) {
+		if layout, ok := strftimeLayout(format); ok {
+			return layout
+		}
+	}
+	format = strings.TrimPrefix(format, "%")
+	if format == "" {
+		return time.DateTime
+	}
+	lower := strings.ToLower(format)
+	if layout, ok := timeLayouts[lower]; ok {
+		return layout
+	}
+	if lower == LayoutRelative || lower == LayoutUnix {
+		return lower
+	}
+	return format
+}
+
+// timeLayoutNames returns the layout names a Time field's Format may use,
+// sorted, each with its leading '%'.
+func timeLayoutNames() []string {
+	names := []string{"%" + LayoutRelative, "%" + LayoutUnix}
+	for name := range timeLayouts {
+		names = append(names, "%"+name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// strftimeVerbs maps the strftime verbs a Time format may use to the Go
+// layout pieces they stand for.
+var strftimeVerbs = map[byte]string{
+	'Y': "2006",
+	'm': "01",
+	'd': "02",
+	'H': "15",
+	'M': "04",
+	'S': "05",
+	'z': "-0700",
+	'Z': "MST",
+	'b': "Jan",
+	'a': "Mon",
+	'p': "PM",
+	'%': "%",
+}
+
+// isStrftime reports whether a Time format is written with strftime verbs,
+// as in "%Y-%m-%d", rather than as a Go layout after the leading '%', as
+// in "%15:04" or "%Z07:00". It is when a '%' and a letter follow the
+// leading '%', or when the format is a single verb such as "%Y".
+func isStrftime(format string) bool {
+	if len(format) == 2 && format[0] == '%' && isLetter(format[1]) {
+		return true
+	}
+	layout := strings.TrimPrefix(format, "%")
+	for i := 0; i+1 < len(layout); i++ {
+		if layout[i] == '%' && isLetter(layout[i+1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// strftimeLayout translates a strftime format to a Go layout. ok is false
+// if it uses a verb not in strftimeVerbs.
+func strftimeLayout(format string) (layout string, ok bool) {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		piece, ok := strftimeVerbs[format[i]]
+		if !ok {
+			return "", false
+		}
+		b.WriteString(piece)
+	}
+	return b.String(), true
+}
+
+// isLetter reports whether b is an ASCII letter.
+func isLetter(b byte) bool {
+	b |= 0x20
+	return b >= 'a' && b <= 'z'
+}
+
+// timeAppender returns the function that writes the values of a Time
+// field. The zero time is written as nothing.
+func timeAppender[T any](f Field[T], now func() time.Time) func(dst []byte, t time.Time) []byte {
+	loc := f.Location
+	switch layout := timeLayout(f.Format); layout {
+	case LayoutRelative:
+		if now == nil {
+			now = time.Now
+		}
+		return func(dst []byte, t time.Time) []byte {
+			if t.IsZero() {
+				return dst
+			}
+			return appendRelative(dst, t, now())
+		}
+	case LayoutUnix:
+		return func(dst []byte, t time.Time) []byte {
+			if t.IsZero() {
+				return dst
+			}
+			return strconv.AppendInt(dst, t.Unix(), 10)
+		}
+	default:
+		return func(dst []byte, t time.Time) []byte {
+			if t.IsZero() {
+				return dst
+			}
+			if loc != nil {
+				t = t.In(loc)
+			}
+			return t.AppendFormat(dst, layout)
+		}
+	}
+}
+
+// appendRelative appends how far t is from now in its largest whole unit:
+// "now" within a second, "3m ago" in the past, "in 2h" in the future.
+func appendRelative(dst []byte, t, now time.Time) []byte {
+	d := now.Sub(t)
+	future := d < 0
+	n := absDuration(d)
+	if n < second {
+		return append(dst, "now"...)
+	}
+	if future {
+		dst = append(dst, "in "...)
+	}
+
+	switch {
+	case n < minute:
+		dst = append(strconv.AppendUint(dst, n/second, 10), 's')
+	case n < hour:
+		dst = append(strconv.AppendUint(dst, n/minute, 10), 'm')
+	case n < day:
+		dst = append(strconv.AppendUint(dst, n/hour, 10), 'h')
+	case n < 365*day:
+		dst = append(strconv.AppendUint(dst, n/day, 10), 'd')
+	default:
+		dst = append(strconv.AppendUint(dst, n/(365*day), 10), 'y')
+	}
+
+	if !future {
+		dst = append(dst, " ago"...)
+	}
+	return dst
+}
+
+// appendDuration appends d in the given style. prec is the number of
+// fraction digits for DurationSeconds and DurationClock.
+func appendDuration(dst []byte, d time.Duration, style DurationStyle, prec int) []byte {
+	switch style {
+	case DurationSeconds:
+		return append(strconv.AppendFloat(dst, d.Seconds(), 'f', prec, 64), 's')
+	case DurationClock:
+		if d < 0 {
+			dst = append(dst, '-')
+		}
+		n := absDuration(d)
+		dst = appendTwoDigits(dst, n/hour)
+		dst = append(dst, ':')
+		dst = appendTwoDigits(dst, n/minute%60)
+		dst = append(dst, ':')
+		dst = appendTwoDigits(dst, n/second%60)
+		if prec > 0 {
+			ns := n % second
+			dst = append(dst, '.')
+			for div := uint64(1e8); prec > 0 && div > 0; prec, div = prec-1, div/10 {
+				dst = append(dst, byte('0'+ns/div%10))
+			}
+		}
+		return dst
+	}
+
+	if d < 0 {
+		dst = append(dst, '-')
+	}
+	n := absDuration(d)
+	switch {
+	case n < microsecond:
+		return append(strconv.AppendUint(dst, n, 10), "ns"...)
+	case n < millisecond:
+		return append(strconv.AppendUint(dst, n/microsecond, 10), "µs"...)
+	case n < second:
+		return append(strconv.AppendUint(dst, n/millisecond, 10), "ms"...)
+	case n < minute:
+		return append(strconv.AppendUint(dst, n/second, 10), 's')
+	case n < hour:
+		return appendUnitPair(dst, n/minute, 'm', n/second%60, 's')
+	case n < day:
+		return appendUnitPair(dst, n/hour, 'h', n/minute%60, 'm')
+	default:
+		return appendUnitPair(dst, n/day, 'd', n/hour%24, 'h')
+	}
+}
+
+// Duration units in nanoseconds, for the magnitudes absDuration returns.
+const (
+	microsecond = uint64(time.Microsecond)
+	millisecond = uint64(time.Millisecond)
+	second      = uint64(time.Second)
+	minute      = uint64(time.Minute)
+	hour        = uint64(time.Hour)
+	day         = 24 * hour
+)
+
+// absDuration returns the magnitude of d in nanoseconds. Unlike -d, it
+// does not overflow for the most negative Duration.
+func absDuration(d time.Duration) uint64 {
+	if d < 0 {
+		return uint64(-(d + 1)) + 1
+	}
+	return uint64(d)
+}
+
+// appendUnitPair appends a value in two units, the second one two digits
+// wide, as in "1h02m".
+func appendUnitPair(dst []byte, major uint64, majorUnit byte, minor uint64, minorUnit byte) []byte {
+	dst = append(strconv.AppendUint(dst, major, 10), majorUnit)
+	return append(appendTwoDigits(dst, minor), minorUnit)
+}
+
+// appendTwoDigits appends n with at least two digits.
+func appendTwoDigits(dst []byte, n uint64) []byte {
+	if n < 10 {
+		dst = append(dst, '0')
+	}
+	return strconv.AppendUint(dst, n, 10)
+}
@@ -0,0 +1,172 @@
+package colprint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Validate checks every field, view and collection of the registry and its
+// sub-registries, and returns all problems found as ValidationErrors, or
+// nil. Call it from a test or at startup to catch mistakes that Register
+// lets through:
+//   - a name registered twice (the last definition is kept), unless it
+//     overrides a field copied by InheritFieldsFrom
+//   - an alias already used by another field
+//   - a name or alias the spec grammar can't parse, such as "a,b" or "-x"
+//   - a missing getter, or no kind at all
+//   - a width of zero or less, or MinWidth above MaxWidth
+//   - a format the field does not take
+//   - collections listing fields that don't exist or including themselves
+//   - view and collection specs that don't compile
+func (r *Registry[T]) Validate() error {
+	var errs ValidationErrors
+	r.validate(&errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validate appends the problems of this registry and its sub-registries
+// to errs.
+func (r *Registry[T]) validate(errs *ValidationErrors) {
+	report := func(name, format string, args ...any) {
+		*errs = append(*errs, &ValidationError{Registry: r.name, Name: name, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	for _, name := range r.duplicates {
+		report(name, "registered more than once")
+	}
+
+	owners := make(map[string]string, len(r.fields))
+	for _, name := range r.fieldOrder {
+		key := strings.ToLower(name)
+		if owner, ok := owners[key]; ok {
+			report(name, "differs only in case from field %q", owner)
+			continue
+		}
+		owners[key] = name
+	}
+	for _, name := range r.fieldOrder {
+		f := r.fields[name]
+		for _, msg := range checkField(f) {
+			report(name, "%s", msg)
+		}
+		for _, alias := range f.Aliases {
+			key := strings.ToLower(alias)
+			if owner, ok := owners[key]; ok && owner != name {
+				report(name, "alias %q is already used by field %q", alias, owner)
+				continue
+			}
+			owners[key] = name
+		}
+	}
+
+	names := make([]string, 0, len(r.defaults))
+	for name := range r.defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := r.collections[name]; ok {
+			members, err := r.memberNames(name, nil)
+			if err != nil {
+				report("@"+name, "%v", err)
+			}
+			for _, m := range members {
+				if _, ok := r.get(m); !ok {
+					report("@"+name, "unknown field %q", m)
+				}
+			}
+		}
+		if spec := r.defaults[name]; spec != "" {
+			if _, _, err := parseSpec(r, spec, nil); err != nil {
+				report("@"+name, "default spec %q: %v", spec, err)
+			}
+		}
+	}
+	for _, name := range r.viewOrder {
+		if _, err := expandRef(r, name, &parseState{}); err != nil {
+			// The report already names the view
+			if re, ok := err.(*refError); ok && re.Name == name {
+				err = re.Err
+			}
+			report("@"+name, "%v", err)
+		}
+	}
+
+	for _, sub := range r.subRegistries {
+		sub.validate(errs)
+	}
+}
+
+// checkField returns what is wrong with a field definition, if anything.
+func checkField[T any](f Field[T]) []string {
+	var problems []string
+	if !validName(f.Name) {
+		problems = append(problems, "name cannot be used in a spec")
+	}
+	for _, alias := range f.Aliases {
+		if !validName(alias) {
+			problems = append(problems, fmt.Sprintf("alias %q cannot be used in a spec", alias))
+		}
+	}
+
+	if f.Width <= 0 {
+		problems = append(problems, fmt.Sprintf("width %d must be positive", f.Width))
+	}
+	if f.MinWidth < 0 || f.MaxWidth < 0 {
+		problems = append(problems, "negative MinWidth or MaxWidth")
+	} else if f.MinWidth > 0 && f.MaxWidth > 0 && f.MinWidth > f.MaxWidth {
+		problems = append(problems, fmt.Sprintf("MinWidth %d exceeds MaxWidth %d", f.MinWidth, f.MaxWidth))
+	}
+
+	// A deprecated field with a replacement is never written
+	if !f.Deprecated || f.Replacement == "" {
+		var missing bool
+		switch f.Kind {
+		case KindString:
+			missing = f.GetString == nil
+		case KindInt:
+			missing = f.GetInt == nil
+		case KindInt64:
+			missing = f.GetInt64 == nil
+		case KindUint64, KindSize:
+			missing = f.GetUint64 == nil
+		case KindFloat:
+			missing = f.GetFloat == nil
+		case KindCustom:
+			missing = f.GetCustom == nil && f.GetCustomFormat == nil
+		case KindTime:
+			missing = f.GetTime == nil
+		case KindDuration:
+			missing = f.GetDuration == nil
+		default:
+			problems = append(problems, "no kind; use String, Int, Float, Time, Duration or Custom")
+		}
+		if missing {
+			problems = append(problems, "missing getter")
+		}
+	}
+
+	if f.Number != nil && !takesNumberFormat(f.Kind) {
+		problems = append(problems, "number format on a field that is not an Int or Float")
+	}
+	if checkFormat(f) != nil {
+		problems = append(problems, fmt.Sprintf("format %q is not valid for this field", f.Format))
+	}
+	return problems
+}
+
+// validName reports whether a spec naming the field as name selects it,
+// rather than parsing as something else or failing to parse.
+func validName(name string) bool {
+	s, err := ParseSpec(name)
+	if err != nil || len(s.Items) != 1 {
+		return false
+	}
+	item := s.Items[0]
+	return item.Kind == ItemField && !item.Remove && item.Name == name &&
+		item.Header == "" && len(item.Modifiers) == 0
+}
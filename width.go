@@ -0,0 +1,280 @@
+package colprint
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Display width measurement.
+//
+// Column widths are measured in terminal cells, not bytes. Most text is one
+// cell per rune, but East Asian wide and fullwidth characters and most emoji
+// take two cells, while combining marks, variation selectors and other
+// format characters take none. Widths are measured per grapheme cluster so
+// that a base character and everything attached to it (accents, emoji
+// modifiers, zero-width-joiner sequences, flag pairs) is measured and
+// truncated as a unit.
+//
+// ASCII text takes a fast path that is a single byte loop: its width is its
+// length and every byte boundary is a cluster boundary.
+
+const (
+	zwj  = 0x200D // zero width joiner
+	vs15 = 0xFE0E // text presentation selector
+	vs16 = 0xFE0F // emoji presentation selector
+)
+
+// runeRange is an inclusive range of code points.
+type runeRange struct {
+	lo, hi rune
+}
+
+// wideRanges lists code points that occupy two terminal cells: the East
+// Asian Wide and Fullwidth characters, and emoji with default emoji
+// presentation. Ranges are sorted for binary search.
+var wideRanges = []runeRange{
+	{0x1100, 0x115F}, // Hangul Jamo initial consonants
+	{0x231A, 0x231B},
+	{0x2329, 0x232A},
+	{0x23E9, 0x23EC},
+	{0x23F0, 0x23F0},
+	{0x23F3, 0x23F3},
+	{0x25FD, 0x25FE},
+	{0x2614, 0x2615},
+	{0x2648, 0x2653},
+	{0x267F, 0x267F},
+	{0x2693, 0x2693},
+	{0x26A1, 0x26A1},
+	{0x26AA, 0x26AB},
+	{0x26BD, 0x26BE},
+	{0x26C4, 0x26C5},
+	{0x26CE, 0x26CE},
+	{0x26D4, 0x26D4},
+	{0x26EA, 0x26EA},
+	{0x26F2, 0x26F3},
+	{0x26F5, 0x26F5},
+	{0x26FA, 0x26FA},
+	{0x26FD, 0x26FD},
+	{0x2705, 0x2705},
+	{0x270A, 0x270B},
+	{0x2728, 0x2728},
+	{0x274C, 0x274C},
+	{0x274E, 0x274E},
+	{0x2753, 0x2755},
+	{0x2757, 0x2757},
+	{0x2795, 0x2797},
+	{0x27B0, 0x27B0},
+	{0x27BF, 0x27BF},
+	{0x2B1B, 0x2B1C},
+	{0x2B50, 0x2B50},
+	{0x2B55, 0x2B55},
+	{0x2E80, 0x303E},   // CJK radicals, Kangxi, CJK symbols and punctuation
+	{0x3041, 0x33FF},   // Hiragana, Katakana, Bopomofo, CJK compatibility
+	{0x3400, 0x4DBF},   // CJK extension A
+	{0x4E00, 0x9FFF},   // CJK unified ideographs
+	{0xA000, 0xA4CF},   // Yi
+	{0xA960, 0xA97F},   // Hangul Jamo extended A
+	{0xAC00, 0xD7A3},   // Hangul syllables
+	{0xF900, 0xFAFF},   // CJK compatibility ideographs
+	{0xFE10, 0xFE19},   // vertical forms
+	{0xFE30, 0xFE6F},   // CJK compatibility and small forms
+	{0xFF00, 0xFF60},   // fullwidth forms
+	{0xFFE0, 0xFFE6},   // fullwidth signs
+	{0x16FE0, 0x16FE4}, // ideographic symbols
+	{0x17000, 0x18CFF}, // Tangut, Khitan
+	{0x1AFF0, 0x1B2FF}, // Kana extended and supplements, Nushu
+	{0x1F004, 0x1F004},
+	{0x1F0CF, 0x1F0CF},
+	{0x1F18E, 0x1F18E},
+	{0x1F191, 0x1F19A},
+	{0x1F200, 0x1F251}, // enclosed ideographic supplement
+	{0x1F260, 0x1F265},
+	{0x1F300, 0x1F320}, // emoji from here on
+	{0x1F32D, 0x1F335},
+	{0x1F337, 0x1F37C},
+	{0x1F37E, 0x1F393},
+	{0x1F3A0, 0x1F3CA},
+	{0x1F3CF, 0x1F3D3},
+	{0x1F3E0, 0x1F3F0},
+	{0x1F3F4, 0x1F3F4},
+	{0x1F3F8, 0x1F43E},
+	{0x1F440, 0x1F440},
+	{0x1F442, 0x1F4FC},
+	{0x1F4FF, 0x1F53D},
+	{0x1F54B, 0x1F54E},
+	{0x1F550, 0x1F567},
+	{0x1F57A, 0x1F57A},
+	{0x1F595, 0x1F596},
+	{0x1F5A4, 0x1F5A4},
+	{0x1F5FB, 0x1F64F},
+	{0x1F680, 0x1F6C5},
+	{0x1F6CC, 0x1F6CC},
+	{0x1F6D0, 0x1F6D2},
+	{0x1F6D5, 0x1F6D7},
+	{0x1F6DC, 0x1F6DF},
+	{0x1F6EB, 0x1F6EC},
+	{0x1F6F4, 0x1F6FC},
+	{0x1F7E0, 0x1F7EB},
+	{0x1F7F0, 0x1F7F0},
+	{0x1F90C, 0x1F93A},
+	{0x1F93C, 0x1F945},
+	{0x1F947, 0x1F9FF},
+	{0x1FA70, 0x1FAFF},
+	{0x20000, 0x2FFFD}, // CJK extensions B-F
+	{0x30000, 0x3FFFD}, // CJK extensions G-H
+}
+
+// inRanges reports whether r falls in one of the sorted ranges.
+func inRanges(r rune, ranges []runeRange) bool {
+	lo, hi := 0, len(ranges)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		switch {
+		case r < ranges[mid].lo:
+			hi = mid
+		case r > ranges[mid].hi:
+			lo = mid + 1
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// runeWidth returns the number of cells r occupies on its own.
+func runeWidth(r rune) int {
+	switch {
+	case r < utf8.RuneSelf:
+		return 1 // ASCII, matching the byte-length fast path
+	case r < 0xA0:
+		return 0 // C1 control characters
+	case r < 0x300:
+		return 1 // fast path for Latin-1 and common Latin
+	case r >= 0x1160 && r <= 0x11FF:
+		return 0 // Hangul medial vowels and final consonants join the syllable
+	case inRanges(r, wideRanges):
+		return 2
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0 // combining marks, variation selectors, ZWJ and friends
+	}
+	return 1
+}
+
+// isRegionalIndicator reports whether r is one of the flag letters that
+// pair up into a single flag emoji.
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// isEmojiModifier reports whether r is a skin tone modifier.
+func isEmojiModifier(r rune) bool {
+	return r >= 0x1F3FB && r <= 0x1F3FF
+}
+
+// decodeRune decodes the rune starting at s[i], returning it and its size
+// in bytes. Invalid UTF-8 decodes as utf8.RuneError of size 1.
+func decodeRune[S text](s S, i int) (rune, int) {
+	if s[i] < utf8.RuneSelf {
+		return rune(s[i]), 1
+	}
+	var buf [utf8.UTFMax]byte
+	n := copy(buf[:], s[i:])
+	return utf8.DecodeRune(buf[:n])
+}
+
+// nextCluster returns the byte index just past the grapheme cluster that
+// starts at s[i], and the number of cells the cluster occupies.
+func nextCluster[S text](s S, i int) (end, width int) {
+	r, size := decodeRune(s, i)
+	end = i + size
+	width = runeWidth(r)
+	flag := isRegionalIndicator(r)
+
+	for end < len(s) {
+		// Anything ASCII starts a new cluster
+		if s[end] < utf8.RuneSelf {
+			break
+		}
+		next, size := decodeRune(s, end)
+		switch {
+		case next == zwj:
+			// The joiner glues the following character to this cluster
+			end += size
+			if end < len(s) {
+				_, size = decodeRune(s, end)
+				end += size
+			}
+			continue
+		case next == vs16:
+			width = 2
+		case next == vs15:
+			width = 1
+		case flag && isRegionalIndicator(next):
+			width = 2
+			flag = false
+		case isEmojiModifier(next) && width == 2:
+			// skin tone folds into the preceding emoji
+		case runeWidth(next) != 0:
+			return end, width
+		}
+		end += size
+	}
+	return end, width
+}
+
+// textWidth returns the number of terminal cells s occupies.
+func textWidth[S text](s S) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			// The previous ASCII byte may be the base of this cluster
+			// (a keycap or an accented letter), so measure from there.
+			if i > 0 {
+				i--
+			}
+			w := i
+			for i < len(s) {
+				end, cw := nextCluster(s, i)
+				w += cw
+				i = end
+			}
+			return w
+		}
+	}
+	return len(s)
+}
+
+// truncateWidth returns the longest prefix of s, as a byte index, that fits
+// in limit cells without splitting a grapheme cluster, along with the width
+// of that prefix. The width may be less than limit when a wide character
+// would straddle the limit.
+func truncateWidth[S text](s S, limit int) (end, width int) {
+	for end < len(s) {
+		if s[end] < utf8.RuneSelf && (end+1 == len(s) || s[end+1] < utf8.RuneSelf) {
+			// ASCII followed by ASCII is a one-cell cluster
+			if width+1 > limit {
+				break
+			}
+			end++
+			width++
+			continue
+		}
+		next, cw := nextCluster(s, end)
+		if width+cw > limit {
+			break
+		}
+		end, width = next, width+cw
+	}
+	return end, width
+}
+
+// skipWidth returns the byte index where the shortest suffix of s that fits
+// in limit cells begins, without splitting a grapheme cluster, along with
+// the width of that suffix. n is the display width of s.
+func skipWidth[S text](s S, n, limit int) (start, width int) {
+	width = n
+	for start < len(s) && width > limit {
+		end, cw := nextCluster(s, start)
+		start, width = end, width-cw
+	}
+	return start, width
+}
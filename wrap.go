@@ -0,0 +1,146 @@
+package colprint
+
+import "bytes"
+
+// maxStackWraps is the number of wrapped columns per program whose pending
+// text can be tracked without allocating.
+const maxStackWraps = 8
+
+// wrapSpan is the text of a wrapped column still to be written: tmp[pos:end].
+type wrapSpan struct {
+	pos, end int
+}
+
+// appendWrappedRow appends a row of a program with wrapped columns.
+//
+// The first line holds every column, with wrapped columns contributing as
+// many words as fit. Continuation lines follow, separated by '\n', while
+// any wrapped column has text left; the other columns are blank on them.
+// The full value of each wrapped column is kept in tmp while the row is
+// built, so no per-row allocation is needed.
+func (p *Program[T]) appendWrappedRow(v *T, tmp, line *[]byte) {
+	var stack [maxStackWraps]wrapSpan
+	spans := stack[:0]
+	if p.wrapCols > len(stack) {
+		spans = make([]wrapSpan, 0, p.wrapCols)
+	}
+
+	for i := range p.columns {
+		if i > 0 {
+			*line = append(*line, p.separator...)
+		}
+		col := &p.columns[i]
+		if !col.wrap {
+			col.write(line, v, tmp)
+			continue
+		}
+		start := len(*tmp)
+		*tmp = col.value(*tmp, v)
+		span := wrapSpan{pos: start, end: len(*tmp)}
+		*line = appendWrapSegment(*line, *tmp, &span, &col.cell)
+		spans = append(spans, span)
+	}
+
+	for wrapPending(spans) {
+		*line = append(*line, '\n')
+		lineStart := len(*line)
+		k := 0
+		for i := range p.columns {
+			if i > 0 {
+				*line = append(*line, p.separator...)
+			}
+			col := &p.columns[i]
+			if !col.wrap {
+				*line = appendSpaces(*line, col.width)
+				continue
+			}
+			*line = appendWrapSegment(*line, *tmp, &spans[k], &col.cell)
+			k++
+		}
+		*line = trimTrailingSpaces(*line, lineStart)
+	}
+}
+
+// wrapPending reports whether any wrapped column has text left.
+func wrapPending(spans []wrapSpan) bool {
+	for i := range spans {
+		if spans[i].pos < spans[i].end {
+			return true
+		}
+	}
+	return false
+}
+
+// appendWrapSegment appends the next line's worth of the span's text,
+// padded to the column, and advances the span past it. An exhausted span
+// yields a blank cell.
+func appendWrapSegment(dst, buf []byte, span *wrapSpan, c *cell) []byte {
+	s := buf[span.pos:span.end]
+	end, next := wrapSegment(s, c.width)
+	span.pos += next
+	return padCell(dst, s[:end], c)
+}
+
+// wrapSegment finds the first line of s when wrapped to width cells.
+//
+// It returns the end of the line's text and the start of the remaining
+// text. Lines break at explicit newlines and at the last space that fits;
+// a word wider than the column is broken at the column edge. Spaces at the
+// break are dropped.
+func wrapSegment(s []byte, width int) (end, next int) {
+	limit := len(s)
+	if i := bytes.IndexByte(s, '\n'); i >= 0 {
+		limit = i
+	}
+	line := s[:limit]
+
+	if textWidth(line) <= width {
+		end, next = limit, limit
+		if next < len(s) {
+			next++ // consume the newline
+		}
+		return trimSpacesBefore(line, end), next
+	}
+
+	fit, _ := truncateWidth(line, width)
+	if fit == 0 {
+		// A single character wider than the column; emit it anyway so the
+		// text keeps moving.
+		fit, _ = nextCluster(line, 0)
+		return fit, fit
+	}
+
+	brk := -1
+	if line[fit] == ' ' {
+		brk = fit
+	} else if i := bytes.LastIndexByte(line[:fit], ' '); i > 0 {
+		brk = i
+	}
+	if brk < 0 {
+		return fit, fit // no space to break at: hard break
+	}
+
+	next = brk
+	for next < limit && s[next] == ' ' {
+		next++
+	}
+	if next == limit && limit < len(s) {
+		next++ // the break fell right before a newline
+	}
+	return trimSpacesBefore(line, brk), next
+}
+
+// trimSpacesBefore returns end moved back over any spaces before it.
+func trimSpacesBefore(s []byte, end int) int {
+	for end > 0 && s[end-1] == ' ' {
+		end--
+	}
+	return end
+}
+
+// trimTrailingSpaces removes spaces from the end of line, but not before
+// index from.
+func trimTrailingSpaces(line []byte, from int) []byte {
+	end := trimSpacesBefore(line[from:], len(line)-from)
+	return line[:from+end]
+}